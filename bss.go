@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"jp.net/wpactl/supplicant"
+)
+
+// channel_number returns the channel of a frequency or 0 if it is in no
+// known band
+func channel_number(freq uint32) int {
+	b := band_of(freq)
+	if b == nil {
+		return 0
+	}
+	if freq == 2484 {
+		return 14
+	}
+	return int(freq-b.base) / 5
+}
+
+// elements_columns renders the decoded information elements as columns of
+// ´scan-results --long´
+func elements_columns(ie *elementsOutput) string {
+	gen := ie.Generation
+	if len(gen) == 0 {
+		gen = "-"
+	}
+	load := "   -"
+	if ie.BSSLoad != nil {
+		load = fmt.Sprintf("%3.0f%%", ie.BSSLoad.ChannelUtilization)
+	}
+	return fmt.Sprintf("%-3s %5d %3d %4s", gen, ie.ChannelWidth, ie.SpatialStreams, load)
+}
+
+func yes_no(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// print_elements prints the decoded information elements in the format
+// of the detail views
+func print_elements(ie *elementsOutput) {
+	if len(ie.Generation) > 0 {
+		fmt.Printf("%-16s 802.11%s\n", "generation", ie.Generation)
+	}
+	fmt.Printf("%-16s %d MHz\n", "channel width", ie.ChannelWidth)
+	if ie.SpatialStreams > 0 {
+		fmt.Printf("%-16s %d\n", "spatial streams", ie.SpatialStreams)
+	}
+	if ie.MaxRate > 0 {
+		fmt.Printf("%-16s %v Mbit/s\n", "max legacy rate", ie.MaxRate)
+	}
+	if len(ie.Country) > 0 {
+		fmt.Printf("%-16s %s\n", "country", ie.Country)
+	}
+	if load := ie.BSSLoad; load != nil {
+		fmt.Printf("%-16s %d stations, %.1f%% channel utilization\n", "bss load", load.StationCount, load.ChannelUtilization)
+	}
+	if md := ie.MobilityDomain; md != nil {
+		fmt.Printf("%-16s %s (FT over DS: %s)\n", "mobility domain", md.ID, yes_no(md.OverDS))
+	}
+	if rm := ie.RMCapabilities; rm != nil {
+		var caps []string
+		for _, c := range []struct {
+			name string
+			set  bool
+		}{
+			{"link-measurement", rm.LinkMeasurement},
+			{"neighbor-report", rm.NeighborReport},
+			{"beacon-passive", rm.BeaconPassive},
+			{"beacon-active", rm.BeaconActive},
+			{"beacon-table", rm.BeaconTable},
+		} {
+			if c.set {
+				caps = append(caps, c.name)
+			}
+		}
+		fmt.Printf("%-16s %s\n", "rm (802.11k)", strings.Join(caps, " "))
+	}
+	fmt.Printf("%-16s %s\n", "bss transition", yes_no(ie.BSSTransition))
+}
+
+// show_bss prints the detail view of the BSS with the BSSID given as
+// second argument
+func (ce *cliExtended) show_bss() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	if ce.Args().Len() < 2 {
+		return usageError{"No BSSID given"}
+	}
+	bssid, err := net.ParseMAC(ce.Args().Get(1))
+	if err != nil {
+		return usageError{fmt.Sprintf("Invalid BSSID ´%s´", ce.Args().Get(1))}
+	}
+	infos, err := iface.BSSInfos(ce.ctx())
+	if err != nil {
+		return err
+	}
+	var info *supplicant.BSSInfo
+	for _, i := range infos {
+		if bytes.Equal(i.BSSID, bssid) {
+			info = i
+		}
+	}
+	if info == nil {
+		return fmt.Errorf("%s: BSS %s not found in the scan results", ifname, bssid)
+	}
+	out := new_bss_output(info)
+	return ce.print_output("bss", out, func() {
+		title := "BSS " + out.BSSID
+		fmt.Println(title + "\n" + strings.Repeat("=", len(title)))
+		fmt.Printf("%-16s %s\n", "ssid", info.SSID)
+		fmt.Printf("%-16s %v\n", "dbus object", out.Path)
+		fmt.Printf("%-16s %v\n", "mode", out.Mode)
+		fmt.Printf("%-16s %d MHz (channel %d)\n", "freq", out.Frequency, channel_number(uint32(out.Frequency)))
+		fmt.Printf("%-16s %d dBm\n", "signal", out.Signal)
+		fmt.Printf("%-16s %ds\n", "age", out.Age)
+		fmt.Printf("%-16s %s\n", "security", strings.Join(out.Security, " "))
+		for _, sec := range []struct {
+			name string
+			out  *securityOutput
+		}{{"wpa", out.WPA}, {"rsn", out.RSN}} {
+			if sec.out != nil {
+				fmt.Printf("%-16s key mgmt %v, pairwise %v, group %s\n", sec.name, sec.out.KeyMgmt, sec.out.Pairwise, sec.out.Group)
+			}
+		}
+		print_elements(out.Elements)
+	})
+}
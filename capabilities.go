@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errMissingCapability is returned if a capability given by --require is
+// not supported
+var errMissingCapability = errors.New("missing capabilities")
+
+type requirementOutput struct {
+	Name      string `json:"name" yaml:"name"`
+	Category  string `json:"category" yaml:"category"`
+	Supported bool   `json:"supported" yaml:"supported"`
+}
+
+type capabilitiesOutput struct {
+	Ifname      string              `json:"ifname" yaml:"ifname"`
+	Pairwise    []string            `json:"pairwise" yaml:"pairwise"`
+	Group       []string            `json:"group" yaml:"group"`
+	GroupMgmt   []string            `json:"group_mgmt" yaml:"group_mgmt"`
+	KeyMgmt     []string            `json:"key_mgmt" yaml:"key_mgmt"`
+	Protocol    []string            `json:"protocol" yaml:"protocol"`
+	AuthAlg     []string            `json:"auth_alg" yaml:"auth_alg"`
+	Scan        []string            `json:"scan" yaml:"scan"`
+	Modes       []string            `json:"modes" yaml:"modes"`
+	MaxScanSSID int32               `json:"max_scan_ssid" yaml:"max_scan_ssid"`
+	Supplicant  []string            `json:"supplicant" yaml:"supplicant"`
+	EapMethods  []string            `json:"eap_methods" yaml:"eap_methods"`
+	Required    []requirementOutput `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// capabilityCategory is a row of the capability matrix
+type capabilityCategory struct {
+	name   string
+	label  string
+	values []string
+}
+
+func (out *capabilitiesOutput) categories() []capabilityCategory {
+	return []capabilityCategory{
+		{"key_mgmt", "KeyMgmt", out.KeyMgmt},
+		{"pairwise", "Pairwise", out.Pairwise},
+		{"group", "Group", out.Group},
+		{"group_mgmt", "GroupMgmt", out.GroupMgmt},
+		{"protocol", "Protocol", out.Protocol},
+		{"auth_alg", "AuthAlg", out.AuthAlg},
+		{"scan", "Scan", out.Scan},
+		{"modes", "Modes", out.Modes},
+		{"supplicant", "Supplicant", out.Supplicant},
+		{"eap", "EapMethods", out.EapMethods},
+	}
+}
+
+// check_requirements evaluates the comma-separated list given by
+// --require. An entry is either a bare capability like ´sae´, which may
+// appear in any category, or qualified by category like ´key_mgmt:sae´.
+func (out *capabilitiesOutput) check_requirements(require []string) error {
+	categories := out.categories()
+	normalize := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+	}
+	for _, list := range require {
+		for _, req := range strings.Split(list, ",") {
+			req = strings.TrimSpace(req)
+			if len(req) == 0 {
+				continue
+			}
+			result := requirementOutput{Name: req}
+			value := req
+			search := categories
+			if kv := strings.SplitN(req, ":", 2); len(kv) == 2 {
+				search = nil
+				for _, cat := range categories {
+					if normalize(cat.name) == normalize(kv[0]) || normalize(cat.label) == normalize(kv[0]) {
+						search = append(search, cat)
+					}
+				}
+				if len(search) == 0 {
+					return usageError{fmt.Sprintf("Unknown capability category ´%s´", kv[0])}
+				}
+				value = kv[1]
+				result.Category = search[0].name
+			}
+		SearchLoop:
+			for _, cat := range search {
+				for _, v := range cat.values {
+					if strings.EqualFold(v, value) {
+						result.Category = cat.name
+						result.Supported = true
+						break SearchLoop
+					}
+				}
+			}
+			out.Required = append(out.Required, result)
+		}
+	}
+	return nil
+}
+
+func (ce *cliExtended) show_capabilities() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	caps, err := iface.Capabilities(ce.ctx())
+	if err != nil {
+		return err
+	}
+	out := &capabilitiesOutput{
+		Ifname:      ifname,
+		Pairwise:    caps.Pairwise,
+		Group:       caps.Group,
+		GroupMgmt:   caps.GroupMgmt,
+		KeyMgmt:     caps.KeyMgmt,
+		Protocol:    caps.Protocol,
+		AuthAlg:     caps.AuthAlg,
+		Scan:        caps.Scan,
+		Modes:       caps.Modes,
+		MaxScanSSID: caps.MaxScanSSID,
+	}
+	if out.Supplicant, err = ce.Supplicant.Capabilities(ce.ctx()); err != nil {
+		return err
+	}
+	if out.EapMethods, err = ce.EapMethods(ce.ctx()); err != nil {
+		return err
+	}
+	if err := out.check_requirements(ce.StringSlice("require")); err != nil {
+		return err
+	}
+	err = ce.print_output("capabilities", out, func() {
+		title := "Capabilities of " + ifname
+		fmt.Println(title + "\n" + strings.Repeat("=", len(title)))
+		for _, cat := range out.categories() {
+			fmt.Printf("%-12s %s\n", cat.label, strings.Join(cat.values, " "))
+		}
+		fmt.Printf("%-12s %d\n", "MaxScanSSID", out.MaxScanSSID)
+		if len(out.Required) > 0 {
+			fmt.Println("\nRequired             Category    Supported\n==========================================")
+			for _, r := range out.Required {
+				supported := "no"
+				if r.Supported {
+					supported = "yes"
+				}
+				fmt.Printf("%-20s %-11s %s\n", r.Name, r.Category, supported)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, r := range out.Required {
+		if !r.Supported {
+			missing = append(missing, r.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: %w: %s", ifname, errMissingCapability, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"jp.net/wpactl/supplicant"
+)
+
+// band is a frequency band with its channel numbering: the center
+// frequency of channel n is base + 5*n MHz. Channels wider than 20 MHz
+// are aligned to the first channel of a segment.
+type band struct {
+	name     string
+	base     uint32
+	low      uint32
+	high     uint32
+	channels []int
+	segments []int
+}
+
+var bands = []*band{
+	{name: "2.4GHz", base: 2407, low: 2400, high: 2500, channels: channel_range(1, 13, 1)},
+	{name: "5GHz", base: 5000, low: 5150, high: 5900, channels: append(append(channel_range(36, 64, 4), channel_range(100, 144, 4)...), channel_range(149, 177, 4)...), segments: []int{36, 100, 149}},
+	{name: "6GHz", base: 5950, low: 5925, high: 7125, channels: channel_range(1, 233, 4), segments: []int{1}},
+}
+
+// channel_widths are the widths accepted by ´--channel <channel>:<width>´
+var channel_widths = map[uint64]bool{20: true, 40: true, 80: true, 160: true, 320: true}
+
+func channel_range(first, last, step int) []int {
+	var channels []int
+	for ch := first; ch <= last; ch += step {
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+// parse_band accepts the band names ´2.4´, ´5´ and ´6´ with an optional
+// ´GHz´ suffix
+func parse_band(s string) (*band, error) {
+	name := strings.TrimSuffix(strings.ToLower(s), "ghz")
+	for _, b := range bands {
+		if strings.TrimSuffix(strings.ToLower(b.name), "ghz") == name {
+			return b, nil
+		}
+	}
+	return nil, usageError{fmt.Sprintf("Invalid band ´%s´, use ´2.4GHz´, ´5GHz´ or ´6GHz´", s)}
+}
+
+// band_of returns the band of the frequency or nil if it is in none
+func band_of(freq uint32) *band {
+	for _, b := range bands {
+		if freq >= b.low && freq <= b.high {
+			return b
+		}
+	}
+	return nil
+}
+
+func (b *band) frequency(ch int) uint32 {
+	if b.base == 2407 && ch == 14 {
+		return 2484
+	}
+	return b.base + 5*uint32(ch)
+}
+
+func (b *band) has_channel(ch int) bool {
+	for _, c := range b.channels {
+		if c == ch {
+			return true
+		}
+	}
+	return false
+}
+
+// center returns the center frequency of the channel of the given width
+// which contains the 20 MHz channel ch, e.g. 5210 MHz (channel 42) for
+// channel 36 and 80 MHz
+func (b *band) center(ch int, width uint32) (uint32, error) {
+	if width == 20 {
+		return b.frequency(ch), nil
+	}
+	/* Number of 20 MHz channels, 4 channel numbers apart */
+	n := int(width / 20)
+	for i := len(b.segments) - 1; i >= 0 && b.has_channel(ch); i-- {
+		start := b.segments[i]
+		if ch < start {
+			continue
+		}
+		first := start + (ch-start)/(4*n)*4*n
+		for c := first; c < first+4*n; c += 4 {
+			if !b.has_channel(c) {
+				return 0, usageError{fmt.Sprintf("Channel %d is not part of a %d MHz channel in the %s band", ch, width, b.name)}
+			}
+		}
+		return b.frequency(first + 2*(n-1)), nil
+	}
+	return 0, usageError{fmt.Sprintf("Channel %d is not part of a %d MHz channel in the %s band", ch, width, b.name)}
+}
+
+// parse_scan_channel converts a channel given as ´<channel>[:<width>]´ to
+// a scan channel. The channel is a channel number of band, or a frequency
+// in MHz if it is 1000 or more. Without band, channel numbers up to 14 are
+// in the 2.4 GHz band, the others in the 5 GHz band. wpa_supplicant takes
+// the center frequency of a wide channel, so a channel number with a width
+// is replaced by the center of the wide channel containing it, while a
+// frequency with a width is taken as center frequency.
+func parse_scan_channel(s string, b *band) (supplicant.ScanChannel, error) {
+	sc := supplicant.ScanChannel{Width: 20}
+	parts := strings.SplitN(s, ":", 2)
+	ch, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return sc, usageError{fmt.Sprintf("Invalid channel ´%s´", s)}
+	}
+	if len(parts) == 2 {
+		width, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil || !channel_widths[width] {
+			return sc, usageError{fmt.Sprintf("Invalid channel width ´%s´, use 20, 40, 80, 160 or 320 MHz", parts[1])}
+		}
+		sc.Width = uint32(width)
+	}
+	if ch >= 1000 {
+		sc.Frequency = uint32(ch)
+		return sc, nil
+	}
+	if b == nil {
+		b = bands[1]
+		if ch <= 14 {
+			b = bands[0]
+		}
+	}
+	if band_of(b.frequency(int(ch))) != b {
+		return sc, usageError{fmt.Sprintf("Channel %d is not in the %s band", ch, b.name)}
+	}
+	sc.Frequency, err = b.center(int(ch), sc.Width)
+	return sc, err
+}
+
+// scan_channels builds the channel list of a scan from the --channel and
+// --band options. Channels are looked up in the given band, a band
+// without channels adds all of its channels.
+func scan_channels(channels, band_names []string) ([]supplicant.ScanChannel, error) {
+	var selected []*band
+	for _, name := range band_names {
+		b, err := parse_band(name)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, b)
+	}
+	if len(selected) > 1 && len(channels) > 0 {
+		return nil, usageError{"--channel can only be combined with a single --band"}
+	}
+	var result []supplicant.ScanChannel
+	if len(channels) == 0 {
+		for _, b := range selected {
+			for _, ch := range b.channels {
+				result = append(result, supplicant.ScanChannel{Frequency: b.frequency(ch), Width: 20})
+			}
+		}
+		return result, nil
+	}
+	var b *band
+	if len(selected) == 1 {
+		b = selected[0]
+	}
+	for _, s := range channels {
+		sc, err := parse_scan_channel(s, b)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sc)
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"jp.net/wpactl/supplicant"
+)
+
+func TestParseScanChannel(t *testing.T) {
+	tests := []struct {
+		channel string
+		band    string
+		freq    uint32
+		width   uint32
+		invalid bool
+	}{
+		{channel: "1", freq: 2412, width: 20},
+		{channel: "14", freq: 2484, width: 20},
+		{channel: "36", freq: 5180, width: 20},
+		{channel: "36:20", freq: 5180, width: 20},
+		{channel: "36:40", freq: 5190, width: 40},
+		{channel: "40:40", freq: 5190, width: 40},
+		{channel: "44:40", freq: 5230, width: 40},
+		{channel: "36:80", freq: 5210, width: 80},
+		{channel: "48:80", freq: 5210, width: 80},
+		{channel: "52:80", freq: 5290, width: 80},
+		{channel: "144:80", freq: 5690, width: 80},
+		{channel: "149:80", freq: 5775, width: 80},
+		{channel: "64:160", freq: 5250, width: 160},
+		{channel: "100:160", freq: 5570, width: 160},
+		{channel: "1", band: "6", freq: 5955, width: 20},
+		{channel: "5:40", band: "6GHz", freq: 5965, width: 40},
+		{channel: "1:320", band: "6", freq: 6105, width: 320},
+		{channel: "2412", freq: 2412, width: 20},
+		{channel: "5210:80", freq: 5210, width: 80},
+		{channel: "6:40", invalid: true},
+		{channel: "132:160", invalid: true},
+		{channel: "165:320", invalid: true},
+		{channel: "37:40", invalid: true},
+		{channel: "36:30", invalid: true},
+		{channel: "36:", invalid: true},
+		{channel: "36:80:1", invalid: true},
+		{channel: "15", invalid: true},
+		{channel: "200", invalid: true},
+		{channel: "36", band: "2.4", invalid: true},
+		{channel: "-1", invalid: true},
+		{channel: "ch36", invalid: true},
+	}
+	for _, tt := range tests {
+		var b *band
+		if len(tt.band) > 0 {
+			var err error
+			if b, err = parse_band(tt.band); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sc, err := parse_scan_channel(tt.channel, b)
+		if tt.invalid {
+			if _, ok := err.(usageError); !ok {
+				t.Errorf("parse_scan_channel(%q, %q) = %+v, %v, want a usage error", tt.channel, tt.band, sc, err)
+			}
+			continue
+		}
+		if err != nil || sc != (supplicant.ScanChannel{Frequency: tt.freq, Width: tt.width}) {
+			t.Errorf("parse_scan_channel(%q, %q) = %+v, %v, want %d MHz, %d MHz wide", tt.channel, tt.band, sc, err, tt.freq, tt.width)
+		}
+	}
+}
+
+func TestScanChannels(t *testing.T) {
+	tests := []struct {
+		channels []string
+		bands    []string
+		want     int
+		first    uint32
+		invalid  bool
+	}{
+		{want: 0},
+		{bands: []string{"2.4"}, want: 13, first: 2412},
+		{bands: []string{"5GHz"}, want: 28, first: 5180},
+		{bands: []string{"6"}, want: 59, first: 5955},
+		{bands: []string{"2.4", "5"}, want: 41, first: 2412},
+		{channels: []string{"1", "36:80"}, want: 2, first: 2412},
+		{channels: []string{"37"}, bands: []string{"6"}, want: 1, first: 6135},
+		{channels: []string{"1"}, bands: []string{"2.4", "5"}, invalid: true},
+		{bands: []string{"60GHz"}, invalid: true},
+		{channels: []string{"36", "x"}, invalid: true},
+	}
+	for _, tt := range tests {
+		channels, err := scan_channels(tt.channels, tt.bands)
+		if tt.invalid {
+			if _, ok := err.(usageError); !ok {
+				t.Errorf("scan_channels(%q, %q) = %v, %v, want a usage error", tt.channels, tt.bands, channels, err)
+			}
+			continue
+		}
+		if err != nil || len(channels) != tt.want {
+			t.Errorf("scan_channels(%q, %q) = %v, %v, want %d channels", tt.channels, tt.bands, channels, err, tt.want)
+			continue
+		}
+		if tt.want > 0 && channels[0].Frequency != tt.first {
+			t.Errorf("scan_channels(%q, %q) starts with %d MHz, want %d MHz", tt.channels, tt.bands, channels[0].Frequency, tt.first)
+		}
+	}
+}
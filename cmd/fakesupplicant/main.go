@@ -0,0 +1,73 @@
+// Command fakesupplicant serves a scripted wpa_supplicant D-Bus service on
+// a private bus, so wpactl can be exercised without wireless hardware.
+//
+//	fakesupplicant -scenario testdata.json &
+//	DBUS_SYSTEM_BUS_ADDRESS=<printed address> wpactl status wlan0
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+	"jp.net/wpactl/supplicant/fake"
+)
+
+func main() {
+	scenario := flag.String("scenario", "", "JSON file describing the interfaces, scan results and failures")
+	address := flag.String("address", "", "serve on this bus instead of starting a private dbus-daemon")
+	flag.Parse()
+
+	/* Errors are returned, not fatal, so the deferred cleanup of run
+	 * stops the private dbus-daemon */
+	if err := run(*scenario, *address); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+// run serves the fake supplicant until SIGINT or SIGTERM
+func run(scenario, address string) error {
+	addr := address
+	if len(addr) == 0 {
+		bus, err := fake.StartBus()
+		if err != nil {
+			return err
+		}
+		defer bus.Close()
+		addr = bus.Address
+	}
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	s, err := fake.New(conn)
+	if err != nil {
+		return err
+	}
+	if len(scenario) > 0 {
+		f, err := os.Open(scenario)
+		if err != nil {
+			return err
+		}
+		sc, err := fake.ReadScenario(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if err := s.Load(sc); err != nil {
+			return err
+		}
+	}
+	fmt.Println(addr)
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, syscall.SIGINT, syscall.SIGTERM)
+	<-sigch
+	return nil
+}
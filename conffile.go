@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"jp.net/wpactl/supplicant"
+)
+
+// confField is a key=value line of wpa_supplicant.conf. The value is in
+// the syntax of the file, i.e. string fields are enclosed in quotes.
+type confField struct {
+	line  int
+	key   string
+	value string
+}
+
+// confNetwork is a network={...} block
+type confNetwork struct {
+	line   int
+	fields []confField
+}
+
+// confFile holds the network blocks and blob-base64 sections of a
+// wpa_supplicant.conf. Global settings are skipped.
+type confFile struct {
+	networks []confNetwork
+	blobs    []supplicant.Blob
+}
+
+// strip_conf_comment removes a comment outside of double quotes, as
+// wpa_supplicant does. A quote escaped in a P"..." string does not end it.
+func strip_conf_comment(line string) string {
+	quoted, escapes := false, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+			escapes = quoted && i > 0 && line[i-1] == 'P'
+		case '#':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// parse_conf_file parses the network blocks and blob-base64 sections of a
+// wpa_supplicant.conf. name is used in error messages.
+func parse_conf_file(name string, data []byte) (*confFile, error) {
+	conf := &confFile{}
+	errorf := func(line int, format string, a ...interface{}) error {
+		return usageError{fmt.Sprintf("%s:%d: ", name, line) + fmt.Sprintf(format, a...)}
+	}
+	var (
+		network   *confNetwork
+		blob      *supplicant.Blob
+		blob_data strings.Builder
+		start     int
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(strip_conf_comment(scanner.Text()))
+		if len(line) == 0 {
+			continue
+		}
+		switch {
+		case blob != nil:
+			if line != "}" {
+				blob_data.WriteString(line)
+				continue
+			}
+			raw, err := base64.StdEncoding.DecodeString(blob_data.String())
+			if err != nil {
+				return nil, errorf(start, "Invalid base64 in blob ´%s´: %v", blob.Name, err)
+			}
+			blob.Data = raw
+			conf.blobs = append(conf.blobs, *blob)
+			blob = nil
+		case network != nil:
+			if line == "}" {
+				conf.networks = append(conf.networks, *network)
+				network = nil
+				continue
+			}
+			eq := strings.IndexByte(line, '=')
+			if eq <= 0 {
+				return nil, errorf(n, "Invalid line ´%s´ in network block", line)
+			}
+			network.fields = append(network.fields, confField{line: n, key: strings.TrimSpace(line[:eq]), value: strings.TrimSpace(line[eq+1:])})
+		case line == "network={":
+			network, start = &confNetwork{line: n}, n
+		case strings.HasPrefix(line, "blob-base64-") && strings.HasSuffix(line, "={"):
+			blob, start = &supplicant.Blob{Name: strings.TrimSuffix(strings.TrimPrefix(line, "blob-base64-"), "={")}, n
+			blob_data.Reset()
+		case strings.IndexByte(line, '=') > 0:
+			/* A global setting like ctrl_interface or country */
+		default:
+			return nil, errorf(n, "Invalid line ´%s´", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if network != nil || blob != nil {
+		return nil, errorf(start, "Block is not closed by ´}´")
+	}
+	return conf, nil
+}
+
+// args converts the fields of the network block to AddNetwork arguments
+func (nw *confNetwork) args(name string) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(nw.fields))
+	for _, f := range nw.fields {
+		if _, dup := args[f.key]; dup {
+			return nil, usageError{fmt.Sprintf("%s:%d: Network field ´%s´ given twice", name, f.line, f.key)}
+		}
+		v, err := conf_field_value(f.key, f.value)
+		if err != nil {
+			return nil, usageError{fmt.Sprintf("%s:%d: %v", name, f.line, err)}
+		}
+		args[f.key] = v
+	}
+	/* AddNetwork creates disabled networks, a block of the file is
+	 * enabled unless it says otherwise */
+	if _, ok := args["disabled"]; !ok {
+		args["disabled"] = int32(0)
+	}
+	return args, nil
+}
+
+// network_import creates the networks and blobs of a wpa_supplicant.conf.
+// All blocks are converted before the first one is added, so an invalid
+// file adds nothing. Blobs which exist with the same content are kept. If
+// wpa_supplicant rejects a block, the blobs and networks added so far are
+// removed again.
+func (ce *cliExtended) network_import() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	name := ce.Path("file")
+	if len(name) == 0 {
+		return usageError{"No configuration file given, use --file"}
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	conf, err := parse_conf_file(name, data)
+	if err != nil {
+		return err
+	}
+	dry_run := ce.Bool("dry-run")
+	out := networkImportOutput{DryRun: dry_run, Blobs: []blobOutput{}, Networks: []importedNetworkOutput{}}
+	args := make([]map[string]interface{}, len(conf.networks))
+	for n, nw := range conf.networks {
+		if args[n], err = nw.args(name); err != nil {
+			return err
+		}
+	}
+	existing, err := iface.Blobs(ce.ctx())
+	if err != nil {
+		return fmt.Errorf("%s: %w", ifname, err)
+	}
+	current := make(map[string][]byte, len(existing))
+	for _, blob := range existing {
+		current[blob.Name] = blob.Data
+	}
+	var lines []string
+	blobs := make([]supplicant.Blob, 0, len(conf.blobs))
+	for _, blob := range conf.blobs {
+		data, ok := current[blob.Name]
+		switch {
+		case !ok:
+			blobs = append(blobs, blob)
+		case bytes.Equal(data, blob.Data):
+			lines = append(lines, fmt.Sprintf("Blob %s unchanged", blob.Name))
+		default:
+			return fmt.Errorf("%s: blob %s: %w with another content, remove it first", ifname, blob.Name, supplicant.ErrBlobExists)
+		}
+	}
+	verb := "Added"
+	if dry_run {
+		verb = "Would add"
+	}
+	var (
+		added_blobs    []string
+		added_networks []*supplicant.Network
+	)
+	rollback := func(err error) error {
+		/* Undo even after Ctrl-C, each call is still limited by --timeout */
+		for _, nw := range added_networks {
+			iface.RemoveNetwork(context.Background(), nw)
+		}
+		for _, name := range added_blobs {
+			iface.RemoveBlob(context.Background(), name)
+		}
+		return err
+	}
+	for _, blob := range blobs {
+		if !dry_run {
+			if err := iface.AddBlob(ce.ctx(), blob); err != nil {
+				return rollback(fmt.Errorf("%s: blob %s: %w", ifname, blob.Name, err))
+			}
+			added_blobs = append(added_blobs, blob.Name)
+		}
+		out.Blobs = append(out.Blobs, blobOutput{Name: blob.Name, Length: len(blob.Data)})
+		lines = append(lines, fmt.Sprintf("%s blob %s, %d bytes", verb, blob.Name, len(blob.Data)))
+	}
+	for n, nw := range conf.networks {
+		imported := importedNetworkOutput{ID: -1, Fields: make(map[string]string, len(nw.fields))}
+		texts := make([]string, 0, len(nw.fields))
+		for _, f := range nw.fields {
+			text := f.value
+			if _, known := network_fields[f.key]; known {
+				text = conf_value(f.key, args[n][f.key])
+			}
+			if secret_fields[f.key] && !ce.Bool("show-secrets") {
+				text = secretMask
+			}
+			imported.Fields[f.key] = text
+			texts = append(texts, f.key+"="+text)
+		}
+		if dry_run {
+			lines = append(lines, fmt.Sprintf("%s network: %s", verb, strings.Join(texts, " ")))
+		} else {
+			added, err := iface.AddNetwork(ce.ctx(), args[n])
+			if err != nil {
+				return rollback(fmt.Errorf("%s: network of line %d: %w", ifname, nw.line, err))
+			}
+			added_networks = append(added_networks, added)
+			imported.ID, imported.Path = added.ID(), added.Path()
+			lines = append(lines, fmt.Sprintf("%s network %d: %s", verb, imported.ID, strings.Join(texts, " ")))
+		}
+		out.Networks = append(out.Networks, imported)
+	}
+	return ce.print_output("network-import", out, func() {
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	})
+}
+
+// write_conf_network renders a network block. The SSID comes first, the
+// other fields in alphabetical order. Unless show_secrets is set, secrets
+// are written as comment.
+func write_conf_network(w *strings.Builder, fields map[string]string, show_secrets bool) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != "ssid" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := fields["ssid"]; ok {
+		keys = append([]string{"ssid"}, keys...)
+	}
+	w.WriteString("network={\n")
+	for _, key := range keys {
+		if secret_fields[key] && !show_secrets {
+			fmt.Fprintf(w, "\t#%s=%s\n", key, secretMask)
+		} else {
+			fmt.Fprintf(w, "\t%s=%s\n", key, fields[key])
+		}
+	}
+	w.WriteString("}\n")
+}
+
+// write_conf_blob renders a blob-base64 section
+func write_conf_blob(w *strings.Builder, blob supplicant.Blob) {
+	const lineLength = 64
+	fmt.Fprintf(w, "blob-base64-%s={\n", blob.Name)
+	data := base64.StdEncoding.EncodeToString(blob.Data)
+	for len(data) > lineLength {
+		w.WriteString(data[:lineLength] + "\n")
+		data = data[lineLength:]
+	}
+	if len(data) > 0 {
+		w.WriteString(data + "\n")
+	}
+	w.WriteString("}\n")
+}
+
+// network_export prints the networks and blobs of the interface as
+// wpa_supplicant.conf
+func (ce *cliExtended) network_export() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	props, err := iface.Properties(ce.ctx())
+	if err != nil {
+		return fmt.Errorf("%s: %w", ifname, err)
+	}
+	networks, err := iface.NetworkInfos(ce.ctx())
+	if err != nil {
+		return fmt.Errorf("%s: %w", ifname, err)
+	}
+	blobs, err := iface.Blobs(ce.ctx())
+	if err != nil {
+		return fmt.Errorf("%s: %w", ifname, err)
+	}
+	show_secrets := ce.Bool("show-secrets")
+	var w strings.Builder
+	fmt.Fprintf(&w, "# Networks of %s, exported by wpactl\n", ifname)
+	if !show_secrets {
+		w.WriteString("# Secrets are commented out, export with --show-secrets to keep them\n")
+	}
+	if ctrl_interface, _ := props["CtrlInterface"].Value().(string); len(ctrl_interface) > 0 {
+		fmt.Fprintf(&w, "ctrl_interface=%s\n", ctrl_interface)
+	} else {
+		fmt.Fprintf(&w, "# %s has no ctrl_interface\n", ifname)
+	}
+	if country, _ := props["Country"].Value().(string); len(country) > 0 {
+		fmt.Fprintf(&w, "country=%s\n", country)
+	}
+	if ap_scan, ok := props["ApScan"].Value().(uint32); ok && ap_scan != 1 {
+		fmt.Fprintf(&w, "ap_scan=%d\n", ap_scan)
+	}
+	for _, nw := range networks {
+		fields := make(map[string]string, len(nw.Properties))
+		for key, v := range nw.Properties {
+			fields[key], _ = v.Value().(string)
+		}
+		w.WriteString("\n")
+		write_conf_network(&w, fields, show_secrets)
+	}
+	for _, blob := range blobs {
+		w.WriteString("\n")
+		write_conf_blob(&w, blob)
+	}
+	fmt.Print(w.String())
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"jp.net/wpactl/supplicant"
+)
+
+func TestParseConfFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		networks [][]confField
+		blobs    []supplicant.Blob
+		invalid  bool
+	}{
+		{name: "empty"},
+		{name: "global settings only", data: "ctrl_interface=DIR=/run/wpa_supplicant GROUP=netdev\nupdate_config=1\ncountry=DE\n"},
+		{
+			name: "network",
+			data: "network={\n\tssid=\"Office\"\n\tkey_mgmt=WPA-PSK\n}\n",
+			networks: [][]confField{{
+				{line: 2, key: "ssid", value: `"Office"`},
+				{line: 3, key: "key_mgmt", value: "WPA-PSK"},
+			}},
+		},
+		{
+			name: "comments and blank lines",
+			data: "# networks\n\nnetwork={ \n  # the office\n\tssid=\"Office # 2\"  # comment\n\tpsk=\"a\"#b\n\n}\n",
+			networks: [][]confField{{
+				{line: 5, key: "ssid", value: `"Office # 2"`},
+				{line: 6, key: "psk", value: `"a"`},
+			}},
+		},
+		{
+			name: "escaped quote",
+			data: "network={\n\tid_str=P\"a\\\"#b\" # comment\n\tphase1=\"a\\\"#b\"\n}\n",
+			networks: [][]confField{{
+				{line: 2, key: "id_str", value: `P"a\"#b"`},
+				{line: 3, key: "phase1", value: `"a\"`},
+			}},
+		},
+		{
+			name: "spaces around the equal sign",
+			data: "network={\n\tscan_ssid = 1\n\tsae_groups=19 20\n}\n",
+			networks: [][]confField{{
+				{line: 2, key: "scan_ssid", value: "1"},
+				{line: 3, key: "sae_groups", value: "19 20"},
+			}},
+		},
+		{
+			name:     "empty network",
+			data:     "network={\n}\n",
+			networks: [][]confField{nil},
+		},
+		{
+			name: "several networks and blobs",
+			data: "network={\n\tssid=\"A\"\n}\nblob-base64-ca={\naGVs\nbG8=\n}\nnetwork={\n\tssid=\"B\"\n}\nblob-base64-empty={\n}\n",
+			networks: [][]confField{
+				{{line: 2, key: "ssid", value: `"A"`}},
+				{{line: 9, key: "ssid", value: `"B"`}},
+			},
+			blobs: []supplicant.Blob{{Name: "ca", Data: []byte("hello")}, {Name: "empty", Data: []byte{}}},
+		},
+		{name: "unclosed network", data: "network={\n\tssid=\"A\"\n", invalid: true},
+		{name: "unclosed blob", data: "blob-base64-ca={\naGVsbG8=\n", invalid: true},
+		{name: "invalid base64", data: "blob-base64-ca={\naGVsbG8\n}\n", invalid: true},
+		{name: "line without value", data: "network={\n\tssid\n}\n", invalid: true},
+		{name: "line without key", data: "network={\n\t=1\n}\n", invalid: true},
+		{name: "stray brace", data: "}\n", invalid: true},
+		{name: "nested network", data: "network={\nnetwork={\n}\n}\n", invalid: true},
+	}
+	for _, tt := range tests {
+		conf, err := parse_conf_file("test.conf", []byte(tt.data))
+		if tt.invalid {
+			if _, ok := err.(usageError); !ok {
+				t.Errorf("%s: parse_conf_file = %+v, %v, want a usage error", tt.name, conf, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parse_conf_file: %v", tt.name, err)
+			continue
+		}
+		var networks [][]confField
+		for _, nw := range conf.networks {
+			networks = append(networks, nw.fields)
+		}
+		if !reflect.DeepEqual(networks, tt.networks) {
+			t.Errorf("%s: networks %+v, want %+v", tt.name, networks, tt.networks)
+		}
+		if !reflect.DeepEqual(conf.blobs, tt.blobs) {
+			t.Errorf("%s: blobs %+v, want %+v", tt.name, conf.blobs, tt.blobs)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jp.net/wpactl/supplicant"
+)
+
+// persistDir holds the per-interface configuration files created by
+// ´up --persist´, named like those of the wpa_supplicant@.service unit
+const persistDir = "/etc/wpa_supplicant"
+
+// defaultCtrlInterface is the ctrl_interface of the configuration files
+// written by wpactl
+const defaultCtrlInterface = "DIR=/run/wpa_supplicant GROUP=netdev"
+
+// persistConfigHeader is written to new per-interface configuration files.
+// update_config=1 allows wpa_supplicant to save the configuration.
+const persistConfigHeader = `# Created by wpactl, updated by "wpactl config save"
+ctrl_interface=` + defaultCtrlInterface + `
+update_config=1
+`
+
+// errNoConfigFile is returned if the configuration of an interface cannot
+// be saved because it was created without a configuration file
+var errNoConfigFile = errors.New("interface was started without a config file, bring it up with ´up --persist´")
+
+func persist_config_path(ifname string) string {
+	return filepath.Join(persistDir, "wpa_supplicant-"+ifname+".conf")
+}
+
+// prepare_persistent_config creates the configuration file if it does not
+// exist yet. An existing file is kept, but a warning is printed if it
+// does not allow updates.
+func prepare_persistent_config(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, []byte(persistConfigHeader), 0600)
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.ReplaceAll(scanner.Text(), " ", "") == "update_config=1" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s does not set update_config=1, ´config save´ will fail\n", path)
+	return nil
+}
+
+func (ce *cliExtended) save_config() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	config, err := iface.ConfigFile(ce.ctx())
+	if err != nil {
+		return err
+	}
+	if len(config) == 0 || config == os.DevNull {
+		return fmt.Errorf("%s: %w", ifname, errNoConfigFile)
+	}
+	if err := iface.SaveConfig(ce.ctx()); err != nil {
+		if errors.Is(err, supplicant.ErrUnknownError) {
+			return fmt.Errorf("%s: cannot write %s, it must be writable and set update_config=1: %w", ifname, config, err)
+		}
+		return err
+	}
+	if ce.text_output() {
+		fmt.Println("Configuration of", ifname, "saved to", config)
+	}
+	return nil
+}
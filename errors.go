@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"jp.net/wpactl/supplicant"
+)
+
+// Exit codes of wpactl. They are part of the command line interface, so
+// existing values must never change.
+const (
+	exitOK                = 0
+	exitFailure           = 1
+	exitUsage             = 2
+	exitNoSupplicant      = 3
+	exitAccessDenied      = 4
+	exitInterfaceUnknown  = 5
+	exitInterfaceExists   = 6
+	exitInvalidArgs       = 7
+	exitNetworkUnknown    = 8
+	exitBlobUnknown       = 9
+	exitNotConnected      = 10
+	exitSupplicantFailure = 11
+	exitTimeout           = 12
+	exitMissingCapability = 13
+	exitNoConfigFile      = 14
+	exitScanFailed        = 15
+	exitScanRejected      = 16
+	exitInterrupted       = 130
+)
+
+// errNoBus is returned if the message bus cannot be connected
+var errNoBus = errors.New("cannot connect to D-Bus")
+
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{errNoBus, exitNoSupplicant},
+	{errMissingCapability, exitMissingCapability},
+	{errNoConfigFile, exitNoConfigFile},
+	{supplicant.ErrScanFailed, exitScanFailed},
+	{supplicant.ErrScanError, exitScanRejected},
+	{context.DeadlineExceeded, exitTimeout},
+	{context.Canceled, exitInterrupted},
+	{supplicant.ErrServiceUnknown, exitNoSupplicant},
+	{supplicant.ErrAccessDenied, exitAccessDenied},
+	{supplicant.ErrInterfaceUnknown, exitInterfaceUnknown},
+	{supplicant.ErrInterfaceExists, exitInterfaceExists},
+	{supplicant.ErrInvalidArgs, exitInvalidArgs},
+	{supplicant.ErrNetworkUnknown, exitNetworkUnknown},
+	{supplicant.ErrBlobUnknown, exitBlobUnknown},
+	{supplicant.ErrNotConnected, exitNotConnected},
+	{supplicant.ErrUnknownError, exitSupplicantFailure},
+}
+
+// usageError is an error caused by wrong command line arguments
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// exit_code maps an error to the exit code of wpactl
+func exit_code(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.As(err, &usageError{}) {
+		return exitUsage
+	}
+	for _, ec := range exitCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	return exitFailure
+}
@@ -6,4 +6,5 @@ require (
 	github.com/godbus/dbus/v5 v5.1.0
 	github.com/urfave/cli/v2 v2.27.7
 	github.com/xrash/smetrics v0.0.0-20250705151800-55b8f293f342 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
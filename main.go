@@ -1,350 +1,989 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/godbus/dbus/v5"
 	"github.com/urfave/cli/v2"
 	"io/ioutil"
+	"jp.net/wpactl/supplicant"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
-const (
-	DbusService = "fi.w1.wpa_supplicant1"
-	DbusPath    = "/fi/w1/wpa_supplicant1"
-	DbusIface   = DbusService
-)
-
 type cliExtended struct {
 	// This is a derived object
 	*cli.Context
-	*dbus.Conn
+	*supplicant.Supplicant
+	output  string
+	timeout time.Duration
 }
 
-func (ce *cliExtended) get_iface_path(ifname string) (obj_iface_path dbus.ObjectPath) {
-	bo := ce.Object(DbusService, DbusPath)
-	if err := bo.Call(DbusIface+".GetInterface", 0, ifname).Store(&obj_iface_path); err != nil {
-		log.Fatal(err)
+func (ce *cliExtended) ctx() context.Context {
+	return ce.Context.Context
+}
+
+func (ce *cliExtended) get_network_interface() (string, error) {
+	args := ce.Args()
+	if !args.Present() {
+		return "", usageError{"No interface name given"}
 	}
-	return
+	return args.First(), nil
 }
 
-func (ce *cliExtended) get_bss_property(bss dbus.ObjectPath, prop string) interface{} {
-	bss_obj := ce.Object(DbusService, bss)
-	propval, err := bss_obj.GetProperty(DbusIface + ".BSS." + prop)
+func (ce *cliExtended) get_iface() (string, *supplicant.Interface, error) {
+	ifname, err := ce.get_network_interface()
+	if err != nil {
+		return "", nil, err
+	}
+	iface, err := ce.GetInterface(ce.ctx(), ifname)
 	if err != nil {
-		log.Fatal(err)
+		return ifname, nil, fmt.Errorf("%s: %w", ifname, err)
 	}
-	return propval.Value()
+	return ifname, iface, nil
 }
 
-func (ce *cliExtended) get_network_interface() string {
-	args := ce.Args()
-	if !args.Present() {
-		log.Fatal("No interface name given")
+func (ce *cliExtended) perform_netop() error {
+	ifn, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	netops := map[string]func(context.Context) error{
+		"reconnect":   iface.Reconnect,
+		"disconnect":  iface.Disconnect,
+		"reassociate": iface.Reassociate,
+		"reattach":    iface.Reattach,
+	}
+	if err := netops[ce.Command.Name](ce.ctx()); err != nil {
+		return err
 	}
-	return args.First()
+	fmt.Println(strings.Title(ce.Command.Name), "interface", ifn)
+	return nil
 }
 
-func (ce *cliExtended) get_obj_iface_path_of_iface() (string, dbus.ObjectPath) {
-	ifname := ce.get_network_interface()
-	return ifname, ce.get_iface_path(ifname)
+func (ce *cliExtended) get_managed_ifaces() ([]interfaceOutput, error) {
+	infos, err := ce.InterfaceInfos(ce.ctx())
+	if err != nil {
+		return nil, err
+	}
+	result := []interfaceOutput{}
+	for _, info := range infos {
+		result = append(result, interfaceOutput{Ifname: info.Ifname, State: info.State, Path: info.Path})
+	}
+	return result, nil
 }
 
-func (ce *cliExtended) perform_netop() {
-	ifn, oip := ce.get_obj_iface_path_of_iface()
-	bo := ce.Object(DbusService, oip)
-	cmd := strings.Title(ce.Command.Name)
-	if err := bo.Call(DbusIface+".Interface."+cmd, 0).Err; err != nil {
-		log.Fatal(err)
+func (ce *cliExtended) list_ifaces() error {
+	ifaces, err := ce.get_managed_ifaces()
+	if err != nil {
+		return err
 	}
-	fmt.Println(cmd, "interface", ifn)
+	return ce.print_output("interface-list", ifaces, func() {
+		fmt.Println("====== Managed interfaces ======")
+		for i, iface := range ifaces {
+			fmt.Printf("%d %-20v %v\n", i, iface.Ifname, iface.State)
+		}
+		fmt.Println("\tHint: use command ´up´ or ´down´ to integrate or disintegrate a link")
+	})
 }
 
-func (ce *cliExtended) get_managed_ifaces() []string {
-	var result []string
-	if managed_ifaces, err := ce.Object(DbusService, DbusPath).GetProperty(DbusIface + ".Interfaces"); err == nil {
-		for _, iface_opath := range managed_ifaces.Value().([]dbus.ObjectPath) {
-			bo := ce.Object(DbusService, iface_opath)
-			if ifname, err := bo.GetProperty(DbusIface + ".Interface.Ifname"); err == nil {
-				if state, err := bo.GetProperty(DbusIface + ".Interface.State"); err == nil {
-					line := fmt.Sprintf("%-20v %v", ifname.Value(), state.Value())
-					result = append(result, line)
-				} else {
-					log.Fatal(err)
-				}
+// subscribe delivers the signals of the given D-Bus interface to a new
+// channel. The returned function cancels the subscription.
+func (ce *cliExtended) subscribe(iface string) (chan *dbus.Signal, func(), error) {
+	match := dbus.WithMatchInterface(iface)
+	if err := ce.Conn().AddMatchSignalContext(ce.ctx(), match); err != nil {
+		return nil, nil, err
+	}
+	sigch := make(chan *dbus.Signal, 16)
+	ce.Conn().Signal(sigch)
+	return sigch, func() {
+		ce.Conn().RemoveSignal(sigch)
+		ce.Conn().RemoveMatchSignal(match)
+	}, nil
+}
+
+// interface_states are the values of the State property of an interface
+var interface_states = map[string]bool{
+	"disconnected":       true,
+	"inactive":           true,
+	"scanning":           true,
+	"authenticating":     true,
+	"associating":        true,
+	"associated":         true,
+	"4way_handshake":     true,
+	"group_handshake":    true,
+	"completed":          true,
+	"unknown":            true,
+	"interface_disabled": true,
+}
+
+// wait_ctx returns a context for waiting on signals which ends after the
+// timeout given by --timeout
+func (ce *cliExtended) wait_ctx() (context.Context, context.CancelFunc) {
+	if ce.timeout > 0 {
+		return context.WithTimeout(ce.ctx(), ce.timeout)
+	}
+	return context.WithCancel(ce.ctx())
+}
+
+// wait_error names the wait which ran out of time, so the user does not
+// only see "context deadline exceeded". Timeouts of single calls are
+// already named by the supplicant package.
+func wait_error(err error, after time.Duration, op string) error {
+	var timeout *supplicant.TimeoutError
+	if errors.As(err, &timeout) || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &supplicant.TimeoutError{Op: op, After: after, Err: err}
+}
+
+func (ce *cliExtended) network_show_list() error {
+	long_listing := ce.Bool("long")
+	var header string
+	if long_listing {
+		header = "Id SSID                             Prio Dis dbus-obj-path\n=========================================================="
+	} else {
+		header = "Id SSID                             Prio Dis\n============================================"
+	}
+	_, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	networks, err := iface.NetworkInfos(ce.ctx())
+	if err != nil {
+		return err
+	}
+	results := make([]networkOutput, 0, len(networks))
+	for _, nw := range networks {
+		results = append(results, new_network_output(nw.ID, nw.Path, nw.Properties))
+	}
+	return ce.print_output("network-list", results, func() {
+		fmt.Println(header)
+		for _, nw := range networks {
+			nprops := nw.Properties
+			ssid_elem, ok := nprops["ssid"]
+			if !ok {
+				ssid_elem = nprops["bssid"]
+			}
+			if long_listing {
+				fmt.Printf("% 2d %-32v %4v %-3v %v\n", nw.ID, ssid_elem.Value(), nprops["priority"], nprops["disabled"], nw.Path)
 			} else {
-				log.Fatal(err)
+				fmt.Printf("% 2d %-32v %4v %-3v\n", nw.ID, ssid_elem.Value(), nprops["priority"], nprops["disabled"])
 			}
 		}
-	} else {
-		log.Fatal(err)
+	})
+}
+
+// network_show prints all fields of a single network in
+// wpa_supplicant.conf syntax
+func (ce *cliExtended) network_show() error {
+	_, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	network, err := ce.select_network(iface)
+	if err != nil {
+		return err
+	}
+	nw, err := network.Info(ce.ctx())
+	if err != nil {
+		return err
+	}
+	out := networkShowOutput{ID: nw.ID, Path: nw.Path, Enabled: nw.Enabled, Fields: make(map[string]string, len(nw.Properties))}
+	keys := make([]string, 0, len(nw.Properties))
+	for key, v := range nw.Properties {
+		text, _ := v.Value().(string)
+		if secret_fields[key] && !ce.Bool("show-secrets") {
+			text = secretMask
+		}
+		out.Fields[key] = text
+		keys = append(keys, key)
 	}
-	return result
+	sort.Strings(keys)
+	return ce.print_output("network", out, func() {
+		fmt.Printf("%-28s %d\n", "Id", out.ID)
+		fmt.Printf("%-28s %s\n", "Path", out.Path)
+		fmt.Printf("%-28s %v\n", "Enabled", out.Enabled)
+		fmt.Println("============================================")
+		for _, key := range keys {
+			fmt.Printf("%-28s %s\n", key, out.Fields[key])
+		}
+	})
+}
+
+// networkFlag is a network field with an option of its own
+type networkFlag struct {
+	field string
+	flag  string
+	usage string
+}
+
+// network_flags are the network fields with an option of their own in
+// ´networks add´ and ´networks set´. The SSID is missing, --ssid selects
+// the network in ´networks set´.
+var network_flags = []networkFlag{
+	{"bssid", "bssid", "BSSID of the entry"},
+	{"psk", "psk", "Preshared key (aka. password)"},
+	{"sae_password", "sae_password", "SAE password"},
+	{"proto", "proto", "list of accepted protocols"},
+	{"key_mgmt", "key_mgmt", "Key management method"},
+	{"pairwise", "pairwise", "list of accepted pairwise (unicast) ciphers for WPA"},
+	{"eap", "eap", "space-separated list of accepted EAP methods"},
+	{"identity", "identity", "identity string for EAP"},
+	{"client_cert", "client_cert", "file path to client certificate file (PEM/DER)"},
+	{"private_key", "private_key", "file path to client private key file (PEM/DER/PFX)"},
+	{"private_key_passwd", "private_key_passwd", "password for private key file"},
+	{"frequency", "frequency", "channel frequency in megahertz"},
+	{"mode", "mode", "IEEE 802.11 operation mode: 0=infrastructure, 1=IBSS, 2=AP"},
+	{"ieee80211w", "ieee80211w", "management frame protection mode (0: disabled, 1: optional, 2: required)"},
+	{"priority", "prio", "priority group"},
 }
 
-func list_ifaces(ifnames []string) {
-	fmt.Println("====== Managed interfaces ======")
-	for i, iname := range ifnames {
-		fmt.Println(i, iname)
+// network_field_flags returns the options of network_flags
+func network_field_flags() []cli.Flag {
+	flags := make([]cli.Flag, 0, len(network_flags))
+	for _, nf := range network_flags {
+		flags = append(flags, &cli.StringFlag{Name: nf.flag, Usage: nf.usage})
 	}
-	fmt.Println("\tHint: use command ´up´ or ´down´ to integrate or disintegrate a link")
+	return flags
 }
 
-func (ce *cliExtended) show_scan_results() {
-	sigch := make(chan *dbus.Signal, 16)
-	if err := ce.AddMatchSignal(dbus.WithMatchInterface(DbusService + ".Interface")); err != nil {
-		log.Fatal(err)
-	}
-	ce.Signal(sigch)
-	defer ce.RemoveSignal(sigch)
-	_, oip := ce.get_obj_iface_path_of_iface()
-	bo := ce.Object(DbusService, oip)
-	if scan_is_ongoing, err := bo.GetProperty(DbusIface + ".Interface.Scanning"); err == nil {
-		if scan_is_ongoing.Value().(bool) {
-			fmt.Print("Interface is still scanning. Waiting ")
-		ScanWaitLoop:
-			for {
-				select {
-				case sig := <-sigch:
-					if sig.Name == DbusService+".Interface.ScanDone" && sig.Body[0].(bool) {
-						fmt.Println(" done")
-						break ScanWaitLoop
-					} else {
-						fmt.Print("+")
-					}
-				case <-time.After(2 * time.Second):
-					fmt.Print("-")
+// network_fields_of_flags returns the fields given by --set, --set-file
+// and the options of network_flags. A field must not be given twice.
+func (ce *cliExtended) network_fields_of_flags() (map[string]interface{}, error) {
+	fields, err := parse_field_assignments(ce.StringSlice("set"), ce.StringSlice("set-file"))
+	if err != nil {
+		return nil, err
+	}
+	for _, nf := range network_flags {
+		if !ce.IsSet(nf.flag) {
+			continue
+		}
+		if _, dup := fields[nf.field]; dup {
+			return nil, usageError{fmt.Sprintf("Network field ´%s´ given by --%s and --set", nf.field, nf.flag)}
+		}
+		if fields[nf.field], err = network_field_value(nf.field, ce.String(nf.flag)); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// network_add creates a network from the options of ´networks add´. It is
+// enabled unless --disabled is given.
+func (ce *cliExtended) network_add() error {
+	_, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	fields, err := ce.network_fields_of_flags()
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{"ssid", "disabled"} {
+		if _, dup := fields[key]; dup && ce.IsSet(key) {
+			return usageError{fmt.Sprintf("Network field ´%s´ given by --%s and --set", key, key)}
+		}
+	}
+	if ce.IsSet("ssid") {
+		if fields["ssid"], err = network_field_value("ssid", ce.String("ssid")); err != nil {
+			return err
+		}
+	}
+	if _, ok := fields["disabled"]; !ok {
+		fields["disabled"] = int32(0)
+		if ce.Bool("disabled") {
+			fields["disabled"] = int32(1)
+		}
+	}
+	if _, ok := fields["ieee80211w"]; !ok {
+		fields["ieee80211w"] = int32(3) /* MGMT_FRAME_PROTECTION_DEFAULT */
+	}
+	nw, err := iface.AddNetwork(ce.ctx(), fields)
+	if err != nil {
+		return err
+	}
+	added := networkAddOutput{ID: nw.ID(), Path: nw.Path()}
+	if err := ce.print_output("network-add", added, func() {
+		fmt.Println(added.ID)
+	}); err != nil {
+		return err
+	}
+	if ce.Bool("results") {
+		return ce.network_show_list()
+	}
+	return nil
+}
+
+// network_set changes the fields of a single network. Only fields whose
+// value differs from the current one are sent.
+func (ce *cliExtended) network_set() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	fields, err := ce.network_fields_of_flags()
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return usageError{"No network field given, use --set or an option like --psk"}
+	}
+	network, err := ce.select_network(iface)
+	if err != nil {
+		return err
+	}
+	nw, err := network.Info(ce.ctx())
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]interface{}, len(fields))
+	out := networkSetOutput{ID: nw.ID, Path: nw.Path, Changed: []string{}}
+	for key, v := range fields {
+		if current, ok := nw.Properties[key].Value().(string); ok && current == conf_value(key, v) {
+			continue
+		}
+		changed[key] = v
+		out.Changed = append(out.Changed, key)
+	}
+	sort.Strings(out.Changed)
+	var messages []string
+	if len(changed) == 0 {
+		messages = append(messages, fmt.Sprintf("Network %d unchanged", nw.ID))
+	} else {
+		if err := network.SetProperties(ce.ctx(), changed); err != nil {
+			return fmt.Errorf("%s: %w", ifname, err)
+		}
+		messages = append(messages, fmt.Sprintf("Network %d: changed %s", nw.ID, strings.Join(out.Changed, ", ")))
+		if ce.Bool("reassociate") {
+			info, err := iface.Info(ce.ctx())
+			if err != nil {
+				return err
+			}
+			if info.CurrentNetwork != nw.Path {
+				messages = append(messages, fmt.Sprintf("Network %d is not the current network, not reassociating", nw.ID))
+			} else {
+				if err := iface.Reassociate(ce.ctx()); err != nil {
+					return fmt.Errorf("%s: %w", ifname, err)
 				}
+				out.Reassociated = true
+				messages = append(messages, "Reassociate interface "+ifname)
 			}
 		}
-	} else {
-		log.Fatal(err)
-	}
-	if v, err := bo.GetProperty(DbusIface + ".Interface.BSSs"); err == nil {
-		fmt.Println("SSID                             BSSID        Freq Sig Age Flags")
-		fmt.Println("================================================================")
-		bss_list := v.Value().([]dbus.ObjectPath)
-		for _, bss := range bss_list {
-			ssid := string(ce.get_bss_property(bss, "SSID").([]byte))
-			bssid := ce.get_bss_property(bss, "BSSID").([]byte)
-			freq := ce.get_bss_property(bss, "Frequency").(uint16)
-			signal := ce.get_bss_property(bss, "Signal").(int16)
-			age := ce.get_bss_property(bss, "Age").(uint32)
-			rsn := ce.get_bss_property(bss, "RSN").(map[string]dbus.Variant)
-			fmt.Printf("%-32s %02x %d %d %3v %v %v\n", ssid, bssid, freq, signal, age, rsn["KeyMgmt"].Value(), rsn["Pairwise"].Value())
+	}
+	return ce.print_output("network-set", out, func() {
+		for _, msg := range messages {
+			fmt.Println(msg)
 		}
-	} else {
-		log.Fatal(err)
+	})
+}
+
+// network_selector_flags returns the flags selecting networks by their
+// network id, SSID, id_str or object path
+func network_selector_flags(action string) []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:    "id",
+			Aliases: []string{"i"},
+			Value:   -1,
+			Usage:   "Network id of the network to " + action,
+		},
+		&cli.StringFlag{
+			Name:  "ssid",
+			Usage: "SSID of the network to " + action,
+		},
+		&cli.StringFlag{
+			Name:  "id-str",
+			Usage: "id_str of the network to " + action,
+		},
+		&cli.StringFlag{
+			Name:  "path",
+			Usage: "D-Bus object path of the network to " + action,
+		},
 	}
 }
 
-func (ce *cliExtended) get_network_properties(netobj dbus.ObjectPath) (retval map[string]dbus.Variant) {
-	bo := ce.Object(DbusService, netobj)
-	if nwprops, err := bo.GetProperty(DbusIface + ".Network.Properties"); err == nil {
-		retval = nwprops.Value().(map[string]dbus.Variant)
-	} else {
-		log.Fatal(err)
+// select_networks returns the networks matching all given selector
+// flags. At least one selector is required and at least one network must
+// match.
+func (ce *cliExtended) select_networks(iface *supplicant.Interface) ([]*supplicant.NetworkInfo, error) {
+	id, ssid, id_str, path := ce.Int("id"), ce.String("ssid"), ce.String("id-str"), ce.String("path")
+	if id < 0 && !ce.IsSet("ssid") && !ce.IsSet("id-str") && len(path) == 0 {
+		return nil, usageError{"No network given, use --id, --ssid, --id-str or --path"}
+	}
+	networks, err := iface.NetworkInfos(ce.ctx())
+	if err != nil {
+		return nil, err
+	}
+	str := func(nw *supplicant.NetworkInfo, key string) (string, bool) {
+		s, ok := nw.Properties[key].Value().(string)
+		return conf_value_text(s), ok
+	}
+	var selected []*supplicant.NetworkInfo
+	for _, nw := range networks {
+		if id >= 0 && nw.ID != id {
+			continue
+		}
+		if len(path) > 0 && string(nw.Path) != path {
+			continue
+		}
+		if v, ok := str(nw, "ssid"); ce.IsSet("ssid") && (!ok || v != ssid) {
+			continue
+		}
+		if v, ok := str(nw, "id_str"); ce.IsSet("id-str") && (!ok || v != id_str) {
+			continue
+		}
+		selected = append(selected, nw)
+	}
+	if len(selected) == 0 {
+		return nil, supplicant.ErrNetworkUnknown
 	}
-	return
+	return selected, nil
 }
 
-func (ce *cliExtended) network_get_obj_list() []dbus.ObjectPath {
-	_, oip := ce.get_obj_iface_path_of_iface()
-	bo := ce.Object(DbusService, oip)
-	if nwlist, err := bo.GetProperty(DbusIface + ".Interface.Networks"); err == nil {
-		return nwlist.Value().([]dbus.ObjectPath)
-	} else {
-		log.Fatal(err)
+// select_network is select_networks for commands acting on a single
+// network
+func (ce *cliExtended) select_network(iface *supplicant.Interface) (*supplicant.Network, error) {
+	selected, err := ce.select_networks(iface)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) > 1 {
+		return nil, usageError{fmt.Sprintf("%d networks match, use --id or --path to choose one", len(selected))}
+	}
+	return ce.Network(selected[0].Path), nil
+}
+
+func (ce *cliExtended) network_set_state(state bool) error {
+	_, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	selected, err := ce.select_networks(iface)
+	if err != nil {
+		return err
+	}
+	for _, nw := range selected {
+		if err := ce.Network(nw.Path).SetEnabled(ce.ctx(), state); err != nil {
+			return err
+		}
+	}
+	if ce.Bool("results") {
+		return ce.network_show_list()
 	}
 	return nil
 }
 
-func (ce *cliExtended) network_show_list() {
-	long_listing := ce.Bool("long")
-	var header string
-	if long_listing {
-		header = "Id SSID                             Prio Dis dbus-obj-path\n=========================================================="
-	} else {
-		header = "Id SSID                             Prio Dis\n============================================"
+// local_addresses returns the IP addresses of the network interface on
+// this host. With a remote ´--bus´ the interface is usually not found
+// here, so the addresses are left out instead of failing the command.
+func local_addresses(ifname string) []string {
+	netif, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return nil
+	}
+	addrlist, err := netif.Addrs()
+	if err != nil {
+		return nil
+	}
+	addresses := make([]string, 0, len(addrlist))
+	for _, addr := range addrlist {
+		addresses = append(addresses, addr.String())
+	}
+	return addresses
+}
+
+func (ce *cliExtended) show_status() error {
+	ifn, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	iface_info, err := iface.Info(ce.ctx())
+	if err != nil {
+		return err
+	}
+	status := statusOutput{Ifname: ifn, Path: iface.Path(), State: iface_info.State, AuthMode: iface_info.CurrentAuthMode, Addresses: []string{}}
+	var info *supplicant.BSSInfo
+	/* Check if interface is really associated with a BSS */
+	if cbss := iface_info.CurrentBSS; cbss.IsValid() && cbss != "/" {
+		if info, err = ce.BSS(cbss).Info(ce.ctx()); err != nil {
+			return err
+		}
+		status.BSS = new_bss_output(info)
 	}
-	fmt.Println(header)
-	for i, nwobj := range ce.network_get_obj_list() {
-		nprops := ce.get_network_properties(nwobj)
-		ssid_elem, ok := nprops["ssid"]
+	status.Addresses = append(status.Addresses, local_addresses(ifn)...)
+	return ce.print_output("status", status, func() {
+		fmt.Println("Interface status")
+		fmt.Println("================")
+		fmt.Printf("%-16s %s\n", "interface", ifn)
+		fmt.Printf("%-16s %v\n", "dbus interface", status.Path)
+		fmt.Printf("%-16s %v\n", "state", status.State)
+		fmt.Printf("%-16v %v\n", "auth mode", status.AuthMode)
+		if info != nil {
+			fmt.Printf("%-16s %02x\n", "bssid", []byte(info.BSSID))
+			fmt.Printf("%-16s %v\n", "freq", info.Frequency)
+			fmt.Printf("%-16s %s\n", "ssid", info.SSID)
+			fmt.Printf("%-16s %v\n", "mode", info.Mode)
+			fmt.Printf("%-16s %v\n", "pairwise cipher", info.RSN.Pairwise)
+			fmt.Printf("%-16s %v\n", "group cipher", info.RSN.Group)
+			fmt.Printf("%-16s %v\n", "key mgmt", info.RSN.KeyMgmt)
+			fmt.Printf("%-16s %v\n", "signal", info.Signal)
+			fmt.Printf("%-16s %v\n", "privacy", info.Privacy)
+			fmt.Printf("%-16s %vs\n", "age", info.Age)
+		}
+		for idx, addr := range status.Addresses {
+			fmt.Printf("%-16s %s\n", "ipaddr"+strconv.Itoa(idx), addr)
+		}
+	})
+}
+
+func (ce *cliExtended) set_interface_property(name string, value interface{}) error {
+	_, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	return iface.SetProperty(ce.ctx(), name, value)
+}
+
+// propertyObject is a supplicant object with generic property access
+type propertyObject interface {
+	Properties(ctx context.Context) (map[string]dbus.Variant, error)
+	DescribeProperties(ctx context.Context) ([]supplicant.PropertyDesc, error)
+	SetProperty(ctx context.Context, name string, value interface{}) error
+}
+
+// propertyAssignment is a property value given on the command line,
+// converted to the type of the property
+type propertyAssignment struct {
+	name  string
+	value interface{}
+}
+
+// show_properties prints all properties of obj as document of the given
+// kind
+func (ce *cliExtended) show_properties(kind string, obj propertyObject) error {
+	props, err := obj.Properties(ce.ctx())
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(props))
+	results := make(map[string]interface{}, len(props))
+	for name, v := range props {
+		names = append(names, name)
+		results[name] = plain_value(v)
+	}
+	sort.Strings(names)
+	return ce.print_output(kind, results, func() {
+		for _, name := range names {
+			fmt.Printf("%-28s %v\n", name, plain_value_text(results[name]))
+		}
+	})
+}
+
+// parse_property_assignments converts name=value arguments to the types
+// announced by the introspection data of obj. what names the kind of
+// object in error messages.
+func (ce *cliExtended) parse_property_assignments(obj propertyObject, what string, args []string) ([]propertyAssignment, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	descs, err := obj.DescribeProperties(ce.ctx())
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]supplicant.PropertyDesc, len(descs))
+	for _, desc := range descs {
+		known[desc.Name] = desc
+	}
+	assignments := make([]propertyAssignment, 0, len(args))
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return nil, usageError{fmt.Sprintf("Invalid argument ´%s´, expected <property>=<value>", arg)}
+		}
+		desc, ok := known[kv[0]]
 		if !ok {
-			ssid_elem = nprops["bssid"]
+			return nil, usageError{fmt.Sprintf("Unknown %s property ´%s´", what, kv[0])}
+		}
+		if !desc.Writable {
+			return nil, usageError{fmt.Sprintf("%s property ´%s´ is read-only", strings.Title(what), kv[0])}
 		}
-		if long_listing {
-			fmt.Printf("% 2d %-32v %4v %-3v %v\n", i, ssid_elem.Value(), nprops["priority"], nprops["disabled"], nwobj)
-		} else {
-			fmt.Printf("% 2d %-32v %4v %-3v\n", i, ssid_elem.Value(), nprops["priority"], nprops["disabled"])
+		v, err := supplicant.ParseValue(desc.Signature, kv[1])
+		if err != nil {
+			return nil, usageError{fmt.Sprintf("%s: %v", kv[0], err)}
 		}
+		assignments = append(assignments, propertyAssignment{desc.Name, v})
 	}
+	return assignments, nil
 }
 
-func (ce *cliExtended) network_set_state(state bool) {
-	_, oip := ce.get_obj_iface_path_of_iface()
-	bo := ce.Object(DbusService, oip)
-	if nwlist, err := bo.GetProperty(DbusIface + ".Interface.Networks"); err == nil {
-		to_change_id := ce.Int("id")
-		for i, nwobj := range nwlist.Value().([]dbus.ObjectPath) {
-			if i == to_change_id {
-				bo = ce.Object(DbusService, nwobj)
-				if err = bo.SetProperty(DbusService+".Network.Enabled", dbus.MakeVariant(state)); err != nil {
-					log.Fatal(err)
-				}
-				break
-			}
+func (ce *cliExtended) set_properties(obj propertyObject, assignments []propertyAssignment) error {
+	for _, a := range assignments {
+		if err := obj.SetProperty(ce.ctx(), a.name, a.value); err != nil {
+			return fmt.Errorf("%s: %w", a.name, err)
 		}
-		if ce.Bool("results") {
-			ce.network_show_list()
+	}
+	return nil
+}
+
+// debug_levels are the valid values of the DebugLevel property, most
+// verbose first
+var debug_levels = []string{"excessive", "msgdump", "debug", "info", "warning", "error"}
+
+// set_global_properties applies the debug flags and the name=value
+// arguments of ´global set´
+func (ce *cliExtended) set_global_properties() error {
+	assignments, err := ce.parse_property_assignments(ce.Supplicant, "global", ce.Args().Slice())
+	if err != nil {
+		return err
+	}
+	if ce.IsSet("debug-level") {
+		level := ce.String("debug-level")
+		valid := false
+		for _, l := range debug_levels {
+			valid = valid || l == level
 		}
-	} else {
-		log.Fatal(err)
+		if !valid {
+			return usageError{fmt.Sprintf("Invalid debug level ´%s´, use one of %s", level, strings.Join(debug_levels, ", "))}
+		}
+		assignments = append(assignments, propertyAssignment{"DebugLevel", level})
+	}
+	if ce.IsSet("debug-timestamp") {
+		assignments = append(assignments, propertyAssignment{"DebugTimestamp", ce.Bool("debug-timestamp")})
+	}
+	if ce.IsSet("debug-show-keys") {
+		assignments = append(assignments, propertyAssignment{"DebugShowKeys", ce.Bool("debug-show-keys")})
+	}
+	if len(assignments) == 0 {
+		return usageError{"Nothing to set"}
 	}
+	if err := ce.warn_show_keys(assignments); err != nil {
+		return err
+	}
+	return ce.set_properties(ce.Supplicant, assignments)
 }
 
-func (ce *cliExtended) show_status() {
-	ifn, oip := ce.get_obj_iface_path_of_iface()
-	bo := ce.Object(DbusService, oip)
-	fmt.Println("Interface status")
-	fmt.Println("================")
-	if state, err := bo.GetProperty(DbusIface + ".Interface.State"); err == nil {
-		fmt.Printf("%-16s %s\n", "interface", ifn)
-		fmt.Printf("%-16s %v\n", "dbus interface", oip)
-		fmt.Printf("%-16s %v\n", "state", state)
-	} else {
-		log.Fatal(err)
+// warn_show_keys warns if the supplicant will log keys and passwords after
+// the assignments: DebugShowKeys takes effect at debug level ´debug´ or
+// more verbose
+func (ce *cliExtended) warn_show_keys(assignments []propertyAssignment) error {
+	props, err := ce.Supplicant.Properties(ce.ctx())
+	if err != nil {
+		return err
 	}
-	if cam, err := bo.GetProperty(DbusIface + ".Interface.CurrentAuthMode"); err == nil {
-		fmt.Printf("%-16v %v\n", "auth mode", cam.Value())
-	} else {
-		log.Fatal(err)
-	}
-	if cbss, err := bo.GetProperty(DbusIface + ".Interface.CurrentBSS"); err == nil {
-		cbss_opath := cbss.Value().(dbus.ObjectPath)
-		/* Check if interface is really associated with a BSS */
-		if cbss_opath != "/" {
-			ssid := string(ce.get_bss_property(cbss_opath, "SSID").([]uint8))
-			bssid := ce.get_bss_property(cbss_opath, "BSSID")
-			frequency := ce.get_bss_property(cbss_opath, "Frequency")
-			mode := ce.get_bss_property(cbss_opath, "Mode")
-			signal := ce.get_bss_property(cbss_opath, "Signal")
-			privacy := ce.get_bss_property(cbss_opath, "Privacy")
-			age := ce.get_bss_property(cbss_opath, "Age")
-			rsn := ce.get_bss_property(cbss_opath, "RSN").(map[string]dbus.Variant)
-			fmt.Printf("%-16s %02x\n", "bssid", bssid)
-			fmt.Printf("%-16s %v\n", "freq", frequency)
-			fmt.Printf("%-16s %v\n", "ssid", ssid)
-			fmt.Printf("%-16s %v\n", "mode", mode)
-			fmt.Printf("%-16s %v\n", "pairwise cipher", rsn["Pairwise"])
-			fmt.Printf("%-16s %v\n", "group cipher", rsn["Group"])
-			fmt.Printf("%-16s %v\n", "key mgmt", rsn["KeyMgmt"])
-			fmt.Printf("%-16s %v\n", "signal", signal)
-			fmt.Printf("%-16s %v\n", "privacy", privacy)
-			fmt.Printf("%-16s %vs\n", "age", age)
-		}
-	}
-	if iface, err := net.InterfaceByName(ifn); err == nil {
-		if addrlist, err := iface.Addrs(); err == nil {
-			for idx, name := range addrlist {
-				fmt.Printf("%-16s %s\n", "ipaddr"+strconv.Itoa(idx), name)
+	level, _ := props["DebugLevel"].Value().(string)
+	show_keys, _ := props["DebugShowKeys"].Value().(bool)
+	for _, a := range assignments {
+		switch a.name {
+		case "DebugLevel":
+			level, _ = a.value.(string)
+		case "DebugShowKeys":
+			show_keys, _ = a.value.(bool)
+		}
+	}
+	if show_keys && (level == "excessive" || level == "msgdump" || level == "debug") {
+		fmt.Fprintln(os.Stderr, "Warning: DebugShowKeys is enabled, keys and passwords are written to the supplicant log")
+	}
+	return nil
+}
+
+// connect_bus opens the connection to the message bus selected by --bus:
+// "system", "session" or a D-Bus address like unix:path=/run/foo
+func connect_bus(bus string) (*dbus.Conn, error) {
+	var conn *dbus.Conn
+	var err error
+	switch {
+	case bus == "system":
+		conn, err = dbus.ConnectSystemBus()
+	case bus == "session":
+		conn, err = dbus.ConnectSessionBus()
+	case strings.Contains(bus, ":"):
+		conn, err = dbus.Connect(bus)
+	default:
+		return nil, usageError{fmt.Sprintf("Invalid bus ´%s´, use ´system´, ´session´ or a D-Bus address", bus)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", errNoBus, bus, err)
+	}
+	return conn, nil
+}
+
+// offline_commands work without wpa_supplicant, they are run without a
+// connection to the message bus
+var offline_commands = map[string]bool{"help": true, "scan diff": true}
+
+// command_path returns the full name of the command selected by args,
+// e.g. "scan diff" for ´sc diff a.json b.json´
+func command_path(commands []*cli.Command, args []string) string {
+	var path []string
+	for _, arg := range args {
+		var found *cli.Command
+		for _, cmd := range commands {
+			if cmd.HasName(arg) {
+				found = cmd
+				break
 			}
-		} else {
-			log.Fatal(err)
 		}
-	} else {
-		log.Fatal(err)
+		if found == nil {
+			break
+		}
+		path = append(path, found.Name)
+		commands = found.Subcommands
 	}
+	return strings.Join(path, " ")
 }
 
-func (ce *cliExtended) set_interface_property(name string, value interface{}) {
-	_, oip := ce.get_obj_iface_path_of_iface()
-	bo := ce.Object(DbusService, oip)
-	if err := bo.SetProperty(DbusIface+".Interface."+name, dbus.MakeVariant(value)); err != nil {
-		log.Fatal(err)
+// help_requested reports whether args ask for the help or the shell
+// completion of a command, which need no connection to the message bus
+func help_requested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "--help", "--generate-bash-completion":
+			return true
+		}
 	}
+	return false
 }
 
 func main() {
-	conn, err := dbus.SystemBus()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer conn.Close()
 	ce := cliExtended{}
-	ce.Conn = conn
+	defer func() {
+		if ce.Supplicant != nil {
+			ce.Conn().Close()
+		}
+	}()
 
 	app := &cli.App{
 		Version:              "0.0.2",
 		EnableBashCompletion: true,
+		/* Repeat slice flags instead, SSIDs may contain commas */
+		DisableSliceFlagSeparator: true,
 		Authors: []*cli.Author{
 			{Name: "Dr. Johann Pfefferl", Email: "pfefferl@gmx.net"},
 		},
 		Action: func(c *cli.Context) error {
 			ce.Context = c
-			list_ifaces(ce.get_managed_ifaces())
-			return nil
+			return ce.list_ifaces()
 		},
 		Usage: "control WPA supplicant through d-bus interface",
+		// Errors are reported by main() to map them to exit codes
+		ExitErrHandler: func(c *cli.Context, err error) {},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "text",
+				EnvVars: []string{"WPACTL_OUTPUT"},
+				Usage:   "Output format of the read commands: " + strings.Join(outputFormats, ", "),
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Value:   30 * time.Second,
+				EnvVars: []string{"WPACTL_TIMEOUT"},
+				Usage:   "Maximum duration of each D-Bus call and of each wait for a signal, 0 means no limit",
+			},
+			&cli.StringFlag{
+				Name:    "bus",
+				Value:   "system",
+				EnvVars: []string{"WPACTL_BUS"},
+				Usage:   "Message bus of wpa_supplicant: ´system´, ´session´ or a D-Bus address, e.g. ´unix:path=/run/foo´ or ´tcp:host=10.0.0.1,port=5555´",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			ce.output = c.String("output")
+			valid_output := false
+			for _, f := range outputFormats {
+				valid_output = valid_output || f == ce.output
+			}
+			if !valid_output {
+				return usageError{fmt.Sprintf("Unknown output format ´%s´, use one of %s", ce.output, strings.Join(outputFormats, ", "))}
+			}
+			ce.timeout = c.Duration("timeout")
+			if offline_commands[command_path(c.App.Commands, c.Args().Slice())] || help_requested(c.Args().Slice()) {
+				return nil
+			}
+			conn, err := connect_bus(c.String("bus"))
+			if err != nil {
+				return err
+			}
+			ce.Supplicant = supplicant.New(conn)
+			ce.Supplicant.Timeout = ce.timeout
+			return nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name: "interface",
 				Subcommands: []*cli.Command{
 					{
-						Name: "list",
+						Name: "list",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.list_ifaces()
+						},
+						Usage: "list managed networks",
+					},
+					{
+						Name: "show",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
+							}
+							return ce.show_properties("interface-properties", iface)
+						},
+						Usage:       "show all properties",
+						ArgsUsage:   "<ifname>",
+						Description: "Report every property of the given interface",
+					},
+					{
+						Name: "set",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
+							}
+							assignments, err := ce.parse_property_assignments(iface, "interface", ce.Args().Tail())
+							if err != nil {
+								return err
+							}
+							if err := ce.set_properties(iface, assignments); err != nil {
+								return err
+							}
+							if apscan := ce.Int("ap_scan"); apscan >= 0 {
+								if err := ce.set_interface_property("ApScan", uint32(apscan)); err != nil {
+									return err
+								}
+							}
+							if country := ce.String("country"); len(country) > 0 {
+								if err := ce.set_interface_property("Country", country); err != nil {
+									return err
+								}
+							}
+							return nil
+						},
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "ap_scan",
+								Usage: "AP scanning/selection: 0, 1 or 2 are valid values",
+								Value: -1,
+							},
+							&cli.StringFlag{
+								Name:  "country",
+								Usage: "The ISO/IEC alpha2 country code",
+							},
+						},
+						Usage:       "set properties",
+						ArgsUsage:   "<ifname> [<property>=<value>...]",
+						Description: "Set writable interface properties by name, e.g. ´BSSExpireAge=300´ or ´FastReauth=false´. List values are separated by commas, byte arrays are given in hex",
+					},
+				},
+				Usage: "list managed network interfaces",
+			},
+			{
+				Name:    "capabilities",
+				Aliases: []string{"caps"},
+				Action: func(c *cli.Context) error {
+					ce.Context = c
+					return ce.show_capabilities()
+				},
+				Usage:       "show supported features of interface and supplicant",
+				ArgsUsage:   "<ifname>",
+				Description: "Report the ciphers, key management methods, modes and EAP methods supported by the driver and the supplicant",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "require",
+						Usage: "Fail unless all given capabilities are supported, e.g. ´sae,ap´ or ´key_mgmt:owe´",
+					},
+				},
+			},
+			{
+				Name: "config",
+				Subcommands: []*cli.Command{
+					{
+						Name: "save",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.save_config()
+						},
+						Usage:       "save the runtime configuration",
+						ArgsUsage:   "<ifname>",
+						Description: "Write the networks and settings of the interface to its configuration file, see ´up --persist´",
+					},
+				},
+				Usage: "manage the configuration file",
+			},
+			{
+				Name: "global",
+				Subcommands: []*cli.Command{
+					{
+						Name: "show",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							list_ifaces(ce.get_managed_ifaces())
-							return nil
+							return ce.show_properties("global-properties", ce.Supplicant)
 						},
-						Usage: "list managed networks",
+						Usage:       "show supplicant-wide properties",
+						Description: "Report every property of the supplicant root object, e.g. DebugLevel, EapMethods and Capabilities",
 					},
 					{
 						Name: "set",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							if apscan := ce.Int("ap_scan"); apscan >= 0 {
-								ce.set_interface_property("ApScan", uint32(apscan))
-							}
-							if country := ce.String("country"); len(country) > 0 {
-								ce.set_interface_property("Country", country)
-							}
-							return nil
+							return ce.set_global_properties()
 						},
 						Flags: []cli.Flag{
-							&cli.IntFlag{
-								Name:  "ap_scan",
-								Usage: "AP scanning/selection: 0, 1 or 2 are valid values",
-								Value: -1,
-							},
 							&cli.StringFlag{
-								Name:  "country",
-								Usage: "The ISO/IEC alpha2 country code",
+								Name:  "debug-level",
+								Usage: "Debug level: " + strings.Join(debug_levels, ", "),
+							},
+							&cli.BoolFlag{
+								Name:  "debug-timestamp",
+								Usage: "Prefix debug messages with a timestamp, use ´--debug-timestamp=false´ to disable",
+							},
+							&cli.BoolFlag{
+								Name:  "debug-show-keys",
+								Usage: "Include keys and passwords in debug messages, use ´--debug-show-keys=false´ to disable",
 							},
 						},
-						Usage:     "set properties",
-						ArgsUsage: "<ifname>",
+						Usage:       "set supplicant-wide properties",
+						ArgsUsage:   "[<property>=<value>...]",
+						Description: "Change debugging at runtime or set writable properties by name, e.g. ´WFDIEs=000601101c440032´",
 					},
 				},
-				Usage: "list managed network interfaces",
+				Usage: "supplicant-wide properties and debug control",
 			},
 			{
 				Name:    "status",
 				Aliases: []string{"st"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ce.show_status()
+					if err := ce.show_status(); err != nil {
+						return err
+					}
 					for loop := c.Duration("loop"); loop > 0; {
-						hour, min, sec := time.Now().Clock()
-						fmt.Printf("clock            %02v:%02v:%02v\n", hour, min, sec)
-						time.Sleep(loop)
-						ce.show_status()
+						if ce.text_output() {
+							hour, min, sec := time.Now().Clock()
+							fmt.Printf("clock            %02v:%02v:%02v\n", hour, min, sec)
+						}
+						select {
+						case <-time.After(loop):
+						case <-ce.ctx().Done():
+							return nil
+						}
+						if err := ce.show_status(); err != nil {
+							return err
+						}
 					}
 					return nil
 				},
@@ -362,22 +1001,55 @@ func main() {
 				Name: "up",
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ci_args := make(map[string]interface{})
-					ci_args["Ifname"] = ce.get_network_interface()
-					ci_args["ConfigFile"] = ce.Path("config")
-					drv := ce.String("driver")
-					if len(drv) > 0 {
-						ci_args["Driver"] = drv
+					ifname, err := ce.get_network_interface()
+					if err != nil {
+						return err
 					}
-					brif := ce.String("bridge")
-					if len(brif) > 0 {
-						ci_args["BridgeIfname"] = brif
+					ci_args := supplicant.InterfaceArgs{
+						Ifname:       ifname,
+						ConfigFile:   ce.Path("config"),
+						Driver:       ce.String("driver"),
+						BridgeIfname: ce.String("bridge"),
 					}
-
-					if err = ce.Object(DbusService, DbusPath).Call(DbusIface+".CreateInterface", 0, ci_args).Err; err != nil {
-						log.Fatal(err)
+					if ce.Bool("persist") {
+						if !ce.IsSet("config") {
+							ci_args.ConfigFile = persist_config_path(ifname)
+						}
+						if err := prepare_persistent_config(ci_args.ConfigFile); err != nil {
+							return err
+						}
 					}
-					fmt.Println("Interface", ci_args["Ifname"], "now managed")
+					state := ce.String("state")
+					if ce.Bool("wait") && !interface_states[state] {
+						return usageError{fmt.Sprintf("Unknown interface state ´%s´", state)}
+					}
+					iface, err := ce.CreateInterface(ce.ctx(), ci_args)
+					if err != nil {
+						return err
+					}
+					fmt.Println("Interface", ifname, "now managed")
+					if !ce.Bool("wait") {
+						return nil
+					}
+					wctx, cancel := ce.wait_ctx()
+					timeout := ce.timeout
+					if ce.IsSet("wait-timeout") {
+						cancel()
+						timeout = ce.Duration("wait-timeout")
+						wctx, cancel = context.WithTimeout(ce.ctx(), timeout)
+					}
+					defer cancel()
+					if info, err := iface.WaitState(wctx, state); err != nil {
+						op := "waiting for state " + state
+						if info != nil {
+							op += ", last state " + info.State
+							if info.DisconnectReason != 0 {
+								op += fmt.Sprintf(", disconnect reason %d", info.DisconnectReason)
+							}
+						}
+						return fmt.Errorf("%s: %w", ifname, wait_error(err, timeout, op))
+					}
+					fmt.Println("Interface", ifname, "reached state", state)
 					return nil
 				},
 				Flags: []cli.Flag{
@@ -387,6 +1059,10 @@ func main() {
 						Value:     os.DevNull,
 						Usage:     "Configuration file path",
 					},
+					&cli.BoolFlag{
+						Name:  "persist",
+						Usage: "Use a per-interface configuration file which ´config save´ updates, created if missing. Without --config it is " + persist_config_path("<ifname>"),
+					},
 					&cli.StringFlag{
 						Name:  "driver",
 						Usage: "Driver name which the interface uses, e.g. ´nl80211´ or ´wired´",
@@ -395,6 +1071,20 @@ func main() {
 						Name:  "bridge",
 						Usage: "Name of the bridge interface to control, e.g. br0",
 					},
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "Wait until the interface reaches the state given by --state",
+					},
+					&cli.StringFlag{
+						Name:  "state",
+						Value: "completed",
+						Usage: "Interface state to wait for, e.g. ´completed´ or ´disconnected´",
+					},
+					&cli.DurationFlag{
+						Name:        "wait-timeout",
+						Usage:       "Maximum time to wait for the state",
+						DefaultText: "value of --timeout",
+					},
 				},
 				Usage:       "bring up network interface",
 				ArgsUsage:   "<ifname>",
@@ -405,9 +1095,12 @@ func main() {
 				Aliases: []string{"dn"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ifname, oip := ce.get_obj_iface_path_of_iface()
-					if err = ce.Object(DbusService, DbusPath).Call(DbusIface+".RemoveInterface", 0, oip).Err; err != nil {
-						log.Fatal(err)
+					ifname, iface, err := ce.get_iface()
+					if err != nil {
+						return err
+					}
+					if err := ce.RemoveInterface(ce.ctx(), iface); err != nil {
+						return err
 					}
 					fmt.Println("Interface", ifname, "no longer managed")
 					return nil
@@ -421,23 +1114,95 @@ func main() {
 				Aliases: []string{"sc"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ifn, oip := ce.get_obj_iface_path_of_iface()
-					iface_obj := ce.Object(DbusService, oip)
-					scan_args := make(map[string]interface{})
-					scan_args["Type"] = ce.String("type")
-					scan_args["AllowRoam"] = ce.Bool("allow-roam")
-
-					fmt.Println("Trigger scan on interface", ifn)
-					if err = iface_obj.Call(DbusIface+".Interface.Scan", 0, scan_args).Err; err != nil {
-						log.Fatal(err)
-					}
-					if ce.Bool("results") {
-						ce.show_scan_results()
-					}
-					return nil
+					return ce.scan()
+				},
+				Usage:     "search for wlan networks on the given interfaces",
+				ArgsUsage: "<ifname> [ifname...]",
+				Subcommands: []*cli.Command{
+					{
+						Name: "diff",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.scan_diff()
+						},
+						Usage:       "compare two scan snapshots",
+						ArgsUsage:   "<before.json> <after.json>",
+						Description: "Report BSSs which appeared, disappeared, changed their channel or security, or whose signal moved by more than --threshold dB between two snapshots saved by ´scan-results --save´",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "threshold",
+								Value: 10,
+								Usage: "Minimum signal change in dB to report",
+							},
+						},
+					},
+					{
+						Name: "abort",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.scan_abort()
+						},
+						Usage:     "stop a running scan",
+						ArgsUsage: "<ifname>",
+					},
+					{
+						Name: "auto",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.scan_auto()
+						},
+						Usage:       "configure background scanning",
+						ArgsUsage:   "<ifname>",
+						Description: "Set how often wpa_supplicant scans while the interface is disconnected. ´exponential´ starts with an interval of --base seconds and multiplies it by --base after each scan up to --limit seconds, ´periodic´ scans every --interval seconds. The setting is not saved to the configuration, use the ´autoscan´ option there",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "mode",
+								Usage: "Autoscan module. Possible values: ´exponential´, ´periodic´",
+							},
+							&cli.UintFlag{
+								Name:  "base",
+								Value: 3,
+								Usage: "First interval and factor of ´exponential´ in seconds",
+							},
+							&cli.UintFlag{
+								Name:  "limit",
+								Value: 300,
+								Usage: "Longest interval of ´exponential´ in seconds",
+							},
+							&cli.UintFlag{
+								Name:  "interval",
+								Value: 30,
+								Usage: "Interval of ´periodic´ in seconds",
+							},
+							&cli.BoolFlag{
+								Name:  "off",
+								Usage: "Disable autoscan",
+							},
+						},
+					},
+					{
+						Name: "watch",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.scan_watch()
+						},
+						Usage:       "show a live table of the BSSs",
+						ArgsUsage:   "<ifname>",
+						Description: "Trigger periodic scans and update the table from the signals of wpa_supplicant. New BSSs are marked with ´+´, vanished ones with ´-´, the trend column shows the recent signal levels. With --output json or yaml the changes are printed as ´bss-event´ documents",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "interval",
+								Value: 10 * time.Second,
+								Usage: "Time between scans, 0 only watches scans triggered by others",
+							},
+							&cli.DurationFlag{
+								Name:  "linger",
+								Value: 15 * time.Second,
+								Usage: "How long new and vanished BSSs stay highlighted",
+							},
+						},
+					},
 				},
-				Usage:     "search for wlan networks on given interface",
-				ArgsUsage: "<ifname>",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:    "type",
@@ -451,11 +1216,27 @@ func main() {
 						Value:   true,
 						Usage:   "´true´ (or absent) to allow a roaming decision based on the results of this scan, ´false´ to prevent a roaming decision.",
 					},
+					&cli.StringSliceFlag{
+						Name:  "ssid",
+						Usage: "Probe for this SSID, finds hidden networks. Repeat for several SSIDs, ´\"\"´ adds a wildcard probe",
+					},
+					&cli.StringSliceFlag{
+						Name:  "channel",
+						Usage: "Scan only this channel, given as ´<channel>[:<width>]´ or frequency in MHz. Repeat for several channels",
+					},
+					&cli.StringSliceFlag{
+						Name:  "band",
+						Usage: "Band of the channels, or scan all channels of the band: ´2.4GHz´, ´5GHz´ or ´6GHz´",
+					},
+					&cli.StringSliceFlag{
+						Name:  "ie",
+						Usage: "Information element in hex to add to the probe requests. Repeat for several elements",
+					},
 					&cli.BoolFlag{
 						Name:    "results",
 						Aliases: []string{"r"},
 						Value:   false,
-						Usage:   "Wait and show scan results",
+						Usage:   "Wait for the end of the scans and show their results",
 					},
 				},
 			},
@@ -464,20 +1245,75 @@ func main() {
 				Aliases: []string{"sr", "scr"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ce.show_scan_results()
-					return nil
+					filter, err := ce.new_bss_filter()
+					if err != nil {
+						return err
+					}
+					return ce.show_scan_results(filter)
 				},
 				Usage:       "get latest scan results",
 				ArgsUsage:   "<ifname>",
 				Description: "Show results of last network scan of given interface",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "long",
+						Usage: "Add columns decoded from the information elements: generation, channel width, spatial streams and channel utilization",
+					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "Order by ´signal´ (strongest first), ´ssid´, ´freq´ or ´age´ (newest first)",
+					},
+					&cli.StringFlag{
+						Name:  "ssid",
+						Usage: "Show only BSSs whose SSID matches this regular expression",
+					},
+					&cli.StringSliceFlag{
+						Name:  "band",
+						Usage: "Show only BSSs in this band: ´2.4´, ´5´ or ´6´. Repeat for several bands",
+					},
+					&cli.IntFlag{
+						Name:  "min-signal",
+						Usage: "Show only BSSs with at least this signal level in dBm, e.g. ´-70´",
+					},
+					&cli.UintFlag{
+						Name:  "max-age",
+						Usage: "Show only BSSs seen in the last given seconds",
+					},
+					&cli.StringSliceFlag{
+						Name:  "security",
+						Usage: "Show only BSSs with this security: " + strings.Join(security_classes, ", ") + ". Repeat for several",
+					},
+					&cli.BoolFlag{
+						Name:  "unique",
+						Usage: "Show only the strongest BSS of each SSID",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Show at most this many BSSs",
+					},
+					&cli.PathFlag{
+						Name:      "save",
+						TakesFile: true,
+						Usage:     "Also write the shown BSSs as JSON snapshot to this file, see ´scan diff´",
+					},
+				},
+			},
+			{
+				Name: "bss",
+				Action: func(c *cli.Context) error {
+					ce.Context = c
+					return ce.show_bss()
+				},
+				Usage:       "show details of a BSS",
+				ArgsUsage:   "<ifname> <bssid>",
+				Description: "Show all properties of a BSS from the scan results, including the decoded information elements: generation, channel width, spatial streams, country, BSS load, mobility domain (802.11r) and radio measurement capabilities (802.11k/v)",
 			},
 			{
 				Name:    "reconnect",
 				Aliases: []string{"rc"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ce.perform_netop()
-					return nil
+					return ce.perform_netop()
 				},
 				Usage:       "like reassociate, but only takes effect if already disconnected",
 				ArgsUsage:   "<ifname>",
@@ -488,8 +1324,7 @@ func main() {
 				Aliases: []string{"dc"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ce.perform_netop()
-					return nil
+					return ce.perform_netop()
 				},
 				Usage:       "disconnect and wait for reassociate/reconnect command before",
 				ArgsUsage:   "<ifname>",
@@ -500,8 +1335,7 @@ func main() {
 				Aliases: []string{"ra"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ce.perform_netop()
-					return nil
+					return ce.perform_netop()
 				},
 				Usage:       "force reassociation",
 				ArgsUsage:   "<ifname>",
@@ -512,8 +1346,7 @@ func main() {
 				Aliases: []string{"rat"},
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					ce.perform_netop()
-					return nil
+					return ce.perform_netop()
 				},
 				Usage:       "force reassociation back to the same BSS",
 				ArgsUsage:   "<ifname>",
@@ -523,16 +1356,26 @@ func main() {
 				Name: "signal_poll",
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					_, oip := ce.get_obj_iface_path_of_iface()
-					bo := ce.Object(DbusService, oip)
-					var siginfo map[string]dbus.Variant
-					if err := bo.Call(DbusIface+".Interface.SignalPoll", 0).Store(&siginfo); err != nil {
-						log.Fatal(err)
+					_, iface, err := ce.get_iface()
+					if err != nil {
+						return err
+					}
+					siginfo, err := iface.SignalPoll(ce.ctx())
+					if err != nil {
+						return err
 					}
+					signames := make([]string, 0, len(siginfo))
+					values := make(map[string]interface{}, len(siginfo))
 					for signame, sigval := range siginfo {
-						fmt.Printf("%-10s %v\n", signame, sigval)
+						signames = append(signames, signame)
+						values[signame] = sigval.Value()
 					}
-					return nil
+					sort.Strings(signames)
+					return ce.print_output("signal-poll", values, func() {
+						for _, signame := range signames {
+							fmt.Printf("%-10s %v\n", signame, siginfo[signame])
+						}
+					})
 				},
 				Usage:     "get signal parameters",
 				ArgsUsage: "<ifname>",
@@ -541,12 +1384,11 @@ func main() {
 				Name: "flush_bss",
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					_, oip := ce.get_obj_iface_path_of_iface()
-					bo := ce.Object(DbusService, oip)
-					if err := bo.Call(DbusIface+".Interface.FlushBSS", 0, c.Uint("age")).Err; err != nil {
-						log.Fatal(err)
+					_, iface, err := ce.get_iface()
+					if err != nil {
+						return err
 					}
-					return nil
+					return iface.FlushBSS(ce.ctx(), uint32(c.Uint("age")))
 				},
 				Usage:     "Flush BSS entries from the cache",
 				ArgsUsage: "<ifname>",
@@ -564,8 +1406,7 @@ func main() {
 						Name: "list",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							ce.network_show_list()
-							return nil
+							return ce.network_show_list()
 						},
 						Usage:       "list configured networks",
 						ArgsUsage:   "<ifname>",
@@ -578,101 +1419,152 @@ func main() {
 						},
 					},
 					{
-						Name: "disable",
+						Name: "show",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							ce.network_set_state(false)
-							return nil
+							return ce.network_show()
 						},
-						Usage:       "disable a network entry",
+						Usage:       "show all fields of a network entry",
+						ArgsUsage:   "<ifname>",
+						Description: "Print the fields of a network as loaded by wpa_supplicant, in wpa_supplicant.conf syntax, and whether it is enabled. Secrets like psk and password are masked",
+						Flags: append(network_selector_flags("show"),
+							&cli.BoolFlag{
+								Name:  "show-secrets",
+								Usage: "Print secrets in clear text",
+							},
+						),
+					},
+					{
+						Name: "set",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.network_set()
+						},
+						Usage:       "change fields of a network entry",
+						ArgsUsage:   "<ifname>",
+						Description: "Change the given fields of a network in place, so it keeps its id and object path. Fields which already have the given value are not sent. A new SSID is set with --set ssid=...",
+						Flags: append(append(network_selector_flags("change"), network_field_flags()...),
+							&cli.StringSliceFlag{
+								Name:  "set",
+								Usage: "Set a network field, e.g. ´bgscan=simple:30:-70:3600´. Repeat for several fields",
+							},
+							&cli.StringSliceFlag{
+								Name:  "set-file",
+								Usage: "Set a network field to the content of a file. Repeat for several fields",
+							},
+							&cli.BoolFlag{
+								Name:  "reassociate",
+								Usage: "Reassociate to apply the change if the network is the current one",
+							},
+						),
+					},
+					{
+						Name: "import",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.network_import()
+						},
+						Usage:       "add the networks of a wpa_supplicant.conf",
 						ArgsUsage:   "<ifname>",
-						Description: "Disable the network by given index name",
+						Description: "Create each network={...} block of the file with AddNetwork and upload its blob-base64-<name> sections with AddBlob. Global settings are skipped. Nothing is added if a block is invalid",
 						Flags: []cli.Flag{
-							&cli.IntFlag{
-								Name:     "id",
-								Aliases:  []string{"i"},
-								Required: true,
-								Usage:    "Id number of the network to disable",
+							&cli.PathFlag{
+								Name:    "file",
+								Aliases: []string{"f"},
+								Usage:   "wpa_supplicant.conf to import",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Only print the networks and blobs which would be added",
+							},
+							&cli.BoolFlag{
+								Name:  "show-secrets",
+								Usage: "Print secrets in clear text",
+							},
+						},
+					},
+					{
+						Name: "export",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.network_export()
+						},
+						Usage:       "print the networks and blobs as wpa_supplicant.conf",
+						ArgsUsage:   "<ifname>",
+						Description: "Render the loaded networks and blobs of the interface as wpa_supplicant.conf, with ctrl_interface, country and ap_scan taken from the interface. Secrets are commented out unless --show-secrets is given. The output is always in wpa_supplicant.conf syntax",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "show-secrets",
+								Usage: "Write secrets in clear text",
 							},
+						},
+					},
+					{
+						Name: "disable",
+						Action: func(c *cli.Context) error {
+							ce.Context = c
+							return ce.network_set_state(false)
+						},
+						Usage:       "disable a network entry",
+						ArgsUsage:   "<ifname>",
+						Description: "Disable the networks matching all given selectors",
+						Flags: append(network_selector_flags("disable"),
 							&cli.BoolFlag{
 								Name:    "results",
 								Aliases: []string{"r"},
 								Usage:   "Show resulting network list",
 							},
-						},
+						),
 					},
 					{
 						Name: "enable",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							ce.network_set_state(true)
-							return nil
+							return ce.network_set_state(true)
 						},
 						Usage:       "enable a network entry",
 						ArgsUsage:   "<ifname>",
-						Description: "Enable the network by given index",
-						Flags: []cli.Flag{
-							&cli.IntFlag{
-								Name:     "id",
-								Aliases:  []string{"i"},
-								Required: true,
-								Usage:    "Id number of the network to enable",
-							},
+						Description: "Enable the networks matching all given selectors",
+						Flags: append(network_selector_flags("enable"),
 							&cli.BoolFlag{
 								Name:    "results",
 								Aliases: []string{"r"},
 								Usage:   "Show resulting network list",
 							},
-						},
+						),
 					},
 					{
 						Name: "remove",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							to_remove_id := ce.Int("id")
-							to_remove_ssid := `"` + ce.String("ssid") + `"`
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
+							}
 							if ce.Bool("all") {
-								if err := bo.Call(DbusIface+".Interface.RemoveAllNetworks", 0).Err; err != nil {
-									log.Fatal(err)
+								if err := iface.RemoveAllNetworks(ce.ctx()); err != nil {
+									return err
 								}
 							} else {
-								for idx, netobj := range ce.network_get_obj_list() {
-									if idx == to_remove_id {
-										if err := bo.Call(DbusIface+".Interface.RemoveNetwork", 0, netobj).Err; err != nil {
-											log.Fatal(err)
-										}
-										break
-									} else if to_remove_ssid != `""` {
-										nprops := ce.get_network_properties(netobj)
-										if nprops["ssid"].Value() == to_remove_ssid {
-											if err := bo.Call(DbusIface+".Interface.RemoveNetwork", 0, netobj).Err; err != nil {
-												log.Fatal(err)
-											}
-										}
+								selected, err := ce.select_networks(iface)
+								if err != nil {
+									return err
+								}
+								for _, nw := range selected {
+									if err := iface.RemoveNetwork(ce.ctx(), ce.Network(nw.Path)); err != nil {
+										return err
 									}
 								}
 							}
 							if ce.Bool("results") {
-								ce.network_show_list()
+								return ce.network_show_list()
 							}
 							return nil
 						},
 						Usage:       "remove a network entry",
 						ArgsUsage:   "<ifname>",
-						Description: "Remove the network by given index",
-						Flags: []cli.Flag{
-							&cli.IntFlag{
-								Name:    "id",
-								Aliases: []string{"i"},
-								Value:   -1,
-								Usage:   "Id number of the network to remove",
-							},
-							&cli.StringFlag{
-								Name:  "ssid",
-								Usage: "SSID of the network to remove",
-							},
+						Description: "Remove the networks matching all given selectors",
+						Flags: append(network_selector_flags("remove"),
 							&cli.BoolFlag{
 								Name:  "all",
 								Usage: "Remove all configured networks from the interface",
@@ -682,41 +1574,37 @@ func main() {
 								Aliases: []string{"r"},
 								Usage:   "Show resulting network list",
 							},
-						},
+						),
 					},
 					{
 						Name: "select",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							to_select_id := ce.Int("id")
-							for idx, netobj := range ce.network_get_obj_list() {
-								if idx == to_select_id {
-									if err := bo.Call(DbusIface+".Interface.SelectNetwork", 0, netobj).Err; err != nil {
-										log.Fatal(err)
-									}
-									break
-								}
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
+							}
+							nw, err := ce.select_network(iface)
+							if err != nil {
+								return err
+							}
+							if err := iface.SelectNetwork(ce.ctx(), nw); err != nil {
+								return err
 							}
 							if ce.Bool("results") {
-								ce.network_show_list()
+								if err := ce.network_show_list(); err != nil {
+									return err
+								}
 							}
 							if ce.Bool("status") {
-								ce.show_status()
+								return ce.show_status()
 							}
 							return nil
 						},
 						Usage:       "select a network entry and disable the others",
 						ArgsUsage:   "<ifname>",
-						Description: "Select the network by given index. The others are disabled automatically",
-						Flags: []cli.Flag{
-							&cli.IntFlag{
-								Name:     "id",
-								Aliases:  []string{"i"},
-								Required: true,
-								Usage:    "Id number of the network to select",
-							},
+						Description: "Select the network matching all given selectors. The others are disabled automatically",
+						Flags: append(network_selector_flags("select"),
 							&cli.BoolFlag{
 								Name:    "results",
 								Aliases: []string{"r"},
@@ -728,85 +1616,21 @@ func main() {
 								Value:   false,
 								Usage:   "Show status of interface",
 							},
-						},
+						),
 					},
 					{
 						Name: "add",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							add_args := make(map[string]interface{})
-							for _, s := range []string{"psk", "ssid", "bssid", "proto", "key_mgmt", "pairwise", "eap", "identity", "client_cert", "private_key", "private_key_passwd", "sae_password"} {
-								v := ce.String(s)
-								if len(v) > 0 {
-									add_args[s] = v
-								}
-							}
-							add_args["disabled"] = 0
-							if c.Bool("disabled") {
-								add_args["disabled"] = 1
-							}
-							add_args["ieee80211w"] = c.Uint("ieee80211w")
-							add_args["priority"] = c.Uint("prio")
-							add_args["mode"] = c.Uint("mode")
-							freq := c.Uint("frequency")
-							if freq > 0 {
-								add_args["frequency"] = freq
-							}
-							var result string
-							if err := bo.Call(DbusIface+".Interface.AddNetwork", 0, add_args).Store(&result); err != nil {
-								log.Fatal(err)
-							}
-							if ce.Bool("results") {
-								ce.network_show_list()
-							}
-							return nil
+							return ce.network_add()
 						},
 						Usage:     "add a network entry",
 						ArgsUsage: "<ifname>",
-						Flags: []cli.Flag{
+						Flags: append(network_field_flags(),
 							&cli.StringFlag{
 								Name:  "ssid",
 								Usage: "SSID of the entry",
 							},
-							&cli.StringFlag{
-								Name:  "bssid",
-								Usage: "BSSID of the entry",
-							},
-							&cli.StringFlag{
-								Name:  "psk",
-								Usage: "Preshared key (aka. password)",
-							},
-							&cli.StringFlag{
-								Name:  "sae_password",
-								Usage: "SAE password",
-							},
-							&cli.StringFlag{
-								Name:  "proto",
-								Usage: "list of accepted protocols",
-							},
-							&cli.StringFlag{
-								Name:  "key_mgmt",
-								Usage: "Key management method",
-							},
-							&cli.StringFlag{
-								Name:  "pairwise",
-								Usage: "list of accepted pairwise (unicast) ciphers for WPA",
-							},
-							&cli.UintFlag{
-								Name:  "frequency",
-								Usage: "channel frequency in megahertz",
-							},
-							&cli.UintFlag{
-								Name:  "mode",
-								Usage: "IEEE 802.11 operation mode: 0=infrastructure, 1=IBSS, 2=AP",
-							},
-							&cli.UintFlag{
-								Name:  "ieee80211w",
-								Value: 3, /* MGMT_FRAME_PROTECTION_DEFAULT */
-								Usage: "management frame protection mode (0: disabled, 1: optional, 2: required)",
-							},
 							&cli.BoolFlag{
 								Name:  "disabled",
 								Value: false,
@@ -816,31 +1640,15 @@ func main() {
 								Name:  "results",
 								Usage: "Show resulting network list",
 							},
-							&cli.StringFlag{
-								Name:  "eap",
-								Usage: "space-separated list of accepted EAP methods",
-							},
-							&cli.StringFlag{
-								Name:  "identity",
-								Usage: "identity string for EAP",
-							},
-							&cli.StringFlag{
-								Name:  "client_cert",
-								Usage: "file path to client certificate file (PEM/DER)",
-							},
-							&cli.StringFlag{
-								Name:  "private_key",
-								Usage: "file path to client private key file (PEM/DER/PFX)",
-							},
-							&cli.StringFlag{
-								Name:  "private_key_passwd",
-								Usage: "password for private key file",
+							&cli.StringSliceFlag{
+								Name:  "set",
+								Usage: "Set a network field, e.g. ´scan_ssid=1´ or ´phase2=auth=MSCHAPV2´. Repeat for several fields",
 							},
-							&cli.UintFlag{
-								Name:  "prio",
-								Usage: "priority group",
+							&cli.StringSliceFlag{
+								Name:  "set-file",
+								Usage: "Set a network field to the content of a file, e.g. ´password=/run/secrets/wifi´. Repeat for several fields",
 							},
-						},
+						),
 					},
 				},
 				Usage:     "operation on configured networks",
@@ -855,24 +1663,30 @@ func main() {
 						Name: "list",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							if bloblist, err := bo.GetProperty(DbusIface + ".Interface.Blobs"); err == nil {
-								blobmap := bloblist.Value().(map[string][]uint8)
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
+							}
+							blobs, err := iface.Blobs(ce.ctx())
+							if err != nil {
+								return err
+							}
+							results := make([]blobOutput, 0, len(blobs))
+							for _, blob := range blobs {
+								results = append(results, blobOutput{Name: blob.Name, Length: len(blob.Data)})
+							}
+							return ce.print_output("blob-list", results, func() {
 								if ce.Bool("no-legend") {
-									for bname, _ := range blobmap {
-										fmt.Println(bname)
+									for _, blob := range results {
+										fmt.Println(blob.Name)
 									}
 								} else {
 									fmt.Println("Name                             Length\n========================================")
-									for bname, bdata := range blobmap {
-										fmt.Printf("%-32s %d\n", bname, len(bdata))
+									for _, blob := range results {
+										fmt.Printf("%-32s %d\n", blob.Name, blob.Length)
 									}
 								}
-							} else {
-								log.Fatal(err)
-							}
-							return nil
+							})
 						},
 						Usage:     "show list of added blobs",
 						ArgsUsage: "<ifname>",
@@ -887,16 +1701,15 @@ func main() {
 						Name: "add",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							if content, err := ioutil.ReadFile(ce.Path("data")); err == nil {
-								if err := bo.Call(DbusIface+".Interface.AddBlob", 0, ce.String("name"), content).Err; err != nil {
-									log.Fatal(err)
-								}
-							} else {
-								log.Fatal(err)
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
 							}
-							return nil
+							content, err := ioutil.ReadFile(ce.Path("data"))
+							if err != nil {
+								return err
+							}
+							return iface.AddBlob(ce.ctx(), supplicant.Blob{Name: ce.String("name"), Data: content})
 						},
 						Usage:     "adds a blob to the interface.",
 						ArgsUsage: "<ifname>",
@@ -918,12 +1731,11 @@ func main() {
 						Name: "remove",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							if err := bo.Call(DbusIface+".Interface.RemoveBlob", 0, ce.String("name")).Err; err != nil {
-								log.Fatal(err)
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
 							}
-							return nil
+							return iface.RemoveBlob(ce.ctx(), ce.String("name"))
 						},
 						Usage:     "remove a blob from the interface.",
 						ArgsUsage: "<ifname>",
@@ -939,16 +1751,15 @@ func main() {
 						Name: "get",
 						Action: func(c *cli.Context) error {
 							ce.Context = c
-							_, oip := ce.get_obj_iface_path_of_iface()
-							bo := ce.Object(DbusService, oip)
-							var blobdata []byte
-							if err = bo.Call(DbusIface+".Interface.GetBlob", 0, ce.String("name")).Store(&blobdata); err != nil {
-								log.Fatal(err)
+							_, iface, err := ce.get_iface()
+							if err != nil {
+								return err
 							}
-							if err = ioutil.WriteFile(ce.Path("output"), blobdata, 0664); err != nil {
-								log.Fatal(err)
+							blob, err := iface.GetBlob(ce.ctx(), ce.String("name"))
+							if err != nil {
+								return err
 							}
-							return nil
+							return ioutil.WriteFile(ce.Path("output"), blob.Data, 0664)
 						},
 						Usage:     "get the data from a previously added blob",
 						ArgsUsage: "<ifname>",
@@ -972,16 +1783,19 @@ func main() {
 				Name: "monitor",
 				Action: func(c *cli.Context) error {
 					ce.Context = c
-					sigch := make(chan *dbus.Signal, 4)
-					if err := ce.AddMatchSignal(dbus.WithMatchInterface(DbusService + ".Interface")); err != nil {
-						log.Fatal(err)
+					sigch, unsubscribe, err := ce.subscribe(supplicant.InterfaceIface)
+					if err != nil {
+						return err
 					}
-					ce.Signal(sigch)
-					defer ce.RemoveSignal(sigch)
-					for sig := range sigch {
-						log.Println(sig)
+					defer unsubscribe()
+					for {
+						select {
+						case sig := <-sigch:
+							log.Println(sig)
+						case <-ce.ctx().Done():
+							return nil
+						}
 					}
-					return nil
 				},
 				Usage:       "show dbus signals for all managed interfaces",
 				Description: "this is a raw dump of the signals sent by wpa_supplicant",
@@ -989,5 +1803,18 @@ func main() {
 		},
 	}
 
-	app.RunAndExitOnError()
+	/* Ctrl-C cancels all pending calls and waits */
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		cancel()
+	}()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exit_code(err))
+	}
 }
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"jp.net/wpactl/supplicant/fake"
+)
+
+/* The tests run wpactl against the fake supplicant on a private
+ * dbus-daemon. The test binary executes itself as wpactl, so the output
+ * and the exit code are checked as a user sees them. */
+
+func TestMain(m *testing.M) {
+	if os.Getenv("WPACTL_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fixture is a fake supplicant on its own bus
+type fixture struct {
+	t   *testing.T
+	bus *fake.Bus
+	s   *fake.Supplicant
+}
+
+// start_fake serves the scenario on a private bus, which is stopped at the
+// end of the test. The test is skipped without dbus-daemon.
+func start_fake(t *testing.T, sc fake.Scenario) *fixture {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	bus, err := fake.StartBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	conn, err := bus.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s, err := fake.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(sc); err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{t: t, bus: bus, s: s}
+}
+
+// result is the outcome of a wpactl run
+type result struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+func (f *fixture) command(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "WPACTL_TEST_MAIN=1", "WPACTL_BUS="+f.bus.Address, "WPACTL_OUTPUT=text", "WPACTL_TIMEOUT=5s", "NO_COLOR=1")
+	return cmd
+}
+
+// run executes wpactl with the given arguments until it exits
+func (f *fixture) run(args ...string) result {
+	f.t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := f.command(args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	return f.result(cmd, cmd.Run(), &stdout, &stderr)
+}
+
+// run_interrupted executes wpactl, calls during once it runs and stops it
+// with SIGINT after a while, as Ctrl-C does
+func (f *fixture) run_interrupted(during func(), args ...string) result {
+	f.t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := f.command(args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		f.t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if during != nil {
+		during()
+	}
+	time.Sleep(700 * time.Millisecond)
+	cmd.Process.Signal(syscall.SIGINT)
+	return f.result(cmd, cmd.Wait(), &stdout, &stderr)
+}
+
+func (f *fixture) result(cmd *exec.Cmd, err error, stdout, stderr *bytes.Buffer) result {
+	f.t.Helper()
+	r := result{stdout: stdout.String(), stderr: stderr.String()}
+	if exit, ok := err.(*exec.ExitError); ok {
+		r.code = exit.ExitCode()
+	} else if err != nil {
+		f.t.Fatalf("%v: %v", cmd.Args, err)
+	}
+	return r
+}
+
+// cmdTest is a wpactl run with its expected exit code. Each string of
+// stdout must be part of the output, nil expects no output at all. Each
+// string of stderr must be part of the error output.
+type cmdTest struct {
+	args   []string
+	code   int
+	stdout []string
+	stderr []string
+}
+
+// run_all runs the tests in order, each one sees the changes of the ones
+// before
+func (f *fixture) run_all(tests []cmdTest) {
+	f.t.Helper()
+	for _, tt := range tests {
+		r := f.run(tt.args...)
+		name := strings.Join(tt.args, " ")
+		if r.code != tt.code {
+			f.t.Errorf("wpactl %s: exit code %d, want %d\nstdout:\n%s\nstderr:\n%s", name, r.code, tt.code, r.stdout, r.stderr)
+			continue
+		}
+		if tt.stdout == nil && len(r.stdout) > 0 {
+			f.t.Errorf("wpactl %s: unexpected output\n%s", name, r.stdout)
+		}
+		for _, want := range tt.stdout {
+			if !strings.Contains(r.stdout, want) {
+				f.t.Errorf("wpactl %s: output lacks %q\n%s", name, want, r.stdout)
+			}
+		}
+		for _, want := range tt.stderr {
+			if !strings.Contains(r.stderr, want) {
+				f.t.Errorf("wpactl %s: error output lacks %q\n%s", name, want, r.stderr)
+			}
+		}
+	}
+}
+
+var (
+	office = fake.BSSConfig{SSID: "Office", BSSID: "00:11:22:33:44:55", Frequency: 2412, Signal: -48, KeyMgmt: []string{"wpa-psk"}}
+	guest  = fake.BSSConfig{SSID: "Guest", BSSID: "00:11:22:33:44:66", Frequency: 5180, Signal: -70}
+	lab    = fake.BSSConfig{SSID: "Lab", BSSID: "00:11:22:33:44:77", Frequency: 5500, Signal: -60, KeyMgmt: []string{"sae"}}
+)
+
+// office_scenario is one interface with a network for Office and a blob
+func office_scenario(config string) fake.Scenario {
+	return fake.Scenario{Interfaces: []fake.InterfaceScenario{{
+		Ifname:      "lo",
+		ConfigFile:  config,
+		BSSs:        []fake.BSSConfig{office},
+		ScanResults: []fake.BSSConfig{office, guest},
+		Networks:    []map[string]string{{"ssid": `"Office"`, "psk": `"secret"`, "key_mgmt": "WPA-PSK", "disabled": "0", "priority": "1"}},
+		Blobs:       map[string][]byte{"ca": []byte("hello")},
+	}}}
+}
+
+func TestInterfaceCommands(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "lo.conf")
+	f := start_fake(t, office_scenario(config))
+	f.run_all([]cmdTest{
+		{args: []string{}, stdout: []string{"0 lo", "disconnected"}},
+		{args: []string{"interface", "list"}, stdout: []string{"0 lo"}},
+		{args: []string{"-o", "json", "interface", "list"}, stdout: []string{`"kind": "interface-list"`, `"ifname": "lo"`}},
+		{args: []string{"interface", "show", "lo"}, stdout: []string{"ApScan                       1", "ConfigFile                   " + config}},
+		{args: []string{"interface", "show", "wl9"}, code: exitInterfaceUnknown},
+		{args: []string{"interface", "set", "--country", "DE", "lo"}},
+		{args: []string{"interface", "show", "lo"}, stdout: []string{"Country                      DE"}},
+		{args: []string{"global", "show"}, stdout: []string{"DebugLevel                   info"}},
+		{args: []string{"global", "set", "--debug-level", "debug"}},
+		{args: []string{"global", "show"}, stdout: []string{"DebugLevel                   debug"}},
+		{args: []string{"capabilities", "lo"}, stdout: []string{"KeyMgmt      none ieee8021x", "MaxScanSSID  4"}},
+		{args: []string{"capabilities", "--require", "sae", "lo"}, stdout: []string{"sae                  key_mgmt    yes"}},
+		{args: []string{"capabilities", "--require", "wep104", "lo"}, code: exitMissingCapability, stdout: []string{"wep104                           no"}},
+		{args: []string{"capabilities"}, code: exitUsage, stdout: nil},
+		{args: []string{"config", "save", "lo"}, stdout: []string{"Configuration of lo saved to " + config}},
+	})
+	if n := f.s.Interface("lo").SaveCount(); n != 1 {
+		t.Errorf("configuration saved %d times, want 1", n)
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	f := start_fake(t, fake.Scenario{})
+	f.run_all([]cmdTest{
+		{args: []string{"up", "wl0"}, stdout: []string{"Interface wl0 now managed"}},
+		{args: []string{"up", "wl0"}, code: exitInterfaceExists},
+		{args: []string{"up", "--wait", "--state", "bogus", "wl1"}, code: exitUsage, stdout: nil},
+		{args: []string{"up", "--wait", "--state", "disconnected", "wl1"}, stdout: []string{"Interface wl1 reached state disconnected"}},
+		{args: []string{"interface", "list"}, stdout: []string{"wl0", "wl1"}},
+		{args: []string{"down", "wl0"}, stdout: []string{"Interface wl0 no longer managed"}},
+		{args: []string{"down", "wl0"}, code: exitInterfaceUnknown, stdout: nil},
+		{args: []string{"up"}, code: exitUsage, stdout: nil},
+	})
+	if f.s.Interface("wl0") != nil || f.s.Interface("wl1") == nil {
+		t.Error("wl0 still managed or wl1 not managed")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	sc := office_scenario("")
+	/* Like an interface of a remote supplicant, missing on this host */
+	sc.Interfaces = append(sc.Interfaces, fake.InterfaceScenario{Ifname: "wlremote0"})
+	f := start_fake(t, sc)
+	f.run_all([]cmdTest{
+		{args: []string{"status", "lo"}, stdout: []string{"interface        lo", "state            disconnected", "ipaddr0"}},
+		{args: []string{"status", "wlremote0"}, stdout: []string{"interface        wlremote0"}},
+		{args: []string{"-o", "json", "status", "wlremote0"}, stdout: []string{`"addresses": []`}},
+		{args: []string{"networks", "select", "--id", "0", "lo"}},
+	})
+	time.Sleep(200 * time.Millisecond)
+	f.run_all([]cmdTest{
+		{args: []string{"status", "lo"}, stdout: []string{"state            completed", "ssid             Office"}},
+		{args: []string{"-o", "json", "status", "lo"}, stdout: []string{`"kind": "status"`, `"state": "completed"`}},
+	})
+	r := f.run_interrupted(nil, "status", "--loop", "200ms", "lo")
+	if r.code != 0 || strings.Count(r.stdout, "Interface status") < 2 {
+		t.Errorf("status --loop: exit code %d, output\n%s", r.code, r.stdout)
+	}
+}
+
+func TestConnectionCommands(t *testing.T) {
+	f := start_fake(t, office_scenario(""))
+	f.run_all([]cmdTest{
+		{args: []string{"disconnect", "lo"}, code: exitNotConnected},
+		{args: []string{"reattach", "lo"}, code: exitNotConnected},
+		{args: []string{"signal_poll", "lo"}, code: exitSupplicantFailure},
+		{args: []string{"reconnect", "lo"}, stdout: []string{"Reconnect interface lo"}},
+	})
+	time.Sleep(200 * time.Millisecond)
+	f.run_all([]cmdTest{
+		{args: []string{"reattach", "lo"}, stdout: []string{"Reattach interface lo"}},
+		{args: []string{"reassociate", "lo"}, stdout: []string{"Reassociate interface lo"}},
+		{args: []string{"disconnect", "lo"}, stdout: []string{"Disconnect interface lo"}},
+		{args: []string{"flush_bss", "--age", "0", "lo"}},
+		{args: []string{"reconnect"}, code: exitUsage, stdout: nil},
+	})
+}
+
+func TestScan(t *testing.T) {
+	f := start_fake(t, office_scenario(""))
+	f.run_all([]cmdTest{
+		{args: []string{"scan", "--ssid", "Office", "--channel", "1", "lo"}, stdout: []string{"Trigger scan on interface lo"}},
+	})
+	args := f.s.Interface("lo").ScanArgs()
+	if len(args) != 1 || args[0]["Type"].Value() != "active" {
+		t.Fatalf("scan arguments %v", args)
+	}
+	if _, ok := args[0]["SSIDs"]; !ok {
+		t.Errorf("scan arguments lack the SSID: %v", args[0])
+	}
+	time.Sleep(100 * time.Millisecond)
+	f.run_all([]cmdTest{
+		{args: []string{"scan", "--results", "lo"}, stdout: []string{"Office                           001122334455 2412 -48", "Guest                            001122334466 5180 -70"}},
+		{args: []string{"scan", "--channel", "200", "lo"}, code: exitUsage, stdout: nil},
+		{args: []string{"scan"}, code: exitUsage, stdout: nil},
+		{args: []string{"scan", "abort", "lo"}, code: exitSupplicantFailure},
+		{args: []string{"scan", "auto", "--mode", "periodic", "--interval", "5", "lo"}, stdout: []string{"Set autoscan of interface lo to periodic:5"}},
+		{args: []string{"scan", "auto", "--mode", "periodic", "lo", "--interval", "5"}, code: exitUsage, stdout: nil},
+		{args: []string{"scan", "auto", "--off", "lo"}, stdout: []string{"Disabled autoscan on interface lo"}},
+	})
+	if arg := f.s.Interface("lo").AutoScanArg(); arg != "" {
+		t.Errorf("autoscan %q after --off", arg)
+	}
+}
+
+func TestScanAbort(t *testing.T) {
+	f := start_fake(t, fake.Scenario{Interfaces: []fake.InterfaceScenario{{Ifname: "lo", ScanHangs: true}}})
+	f.run_all([]cmdTest{
+		{args: []string{"scan", "lo"}, stdout: []string{"Trigger scan on interface lo"}},
+		{args: []string{"scan", "lo"}, code: exitScanRejected, stdout: []string{"Trigger scan on interface lo"}, stderr: []string{"lo: scan request rejected (Scan request rejected)"}},
+		{args: []string{"scan", "abort", "lo"}, stdout: []string{"Aborted scan on interface lo"}},
+		{args: []string{"--timeout", "300ms", "scan", "--results", "lo"}, code: exitTimeout, stdout: []string{"Trigger scan on interface lo"}, stderr: []string{"lo: timed out after 300ms waiting for scan results"}},
+		{args: []string{"--timeout", "300ms", "scan-results", "lo"}, code: exitTimeout, stderr: []string{"lo: timed out after 300ms waiting for scan results"}},
+		{args: []string{"--timeout", "1ns", "interface", "show", "lo"}, code: exitTimeout, stderr: []string{"timed out after 1ns waiting for the reply to GetInterface"}},
+		{args: []string{"up", "--wait", "--wait-timeout", "200ms", "wl0"}, code: exitTimeout, stdout: []string{"Interface wl0 now managed"}, stderr: []string{"wl0: timed out after 200ms waiting for state completed, last state disconnected"}},
+	})
+}
+
+func TestUpWaitDisconnectReason(t *testing.T) {
+	f := start_fake(t, fake.Scenario{})
+	r := f.run_interrupted(func() {
+		iface := f.s.Interface("wl0")
+		iface.SetProperty("DisconnectReason", int32(15))
+		iface.SetState("disconnected")
+	}, "up", "--wait", "--wait-timeout", "1s", "wl0")
+	if r.code != exitTimeout || !strings.Contains(r.stderr, "wl0: timed out after 1s waiting for state completed, last state disconnected, disconnect reason 15") {
+		t.Errorf("up --wait: exit code %d, output\n%s\n%s", r.code, r.stdout, r.stderr)
+	}
+}
+
+func TestScanSeveralInterfaces(t *testing.T) {
+	f := start_fake(t, fake.Scenario{Interfaces: []fake.InterfaceScenario{
+		{Ifname: "lo", ScanResults: []fake.BSSConfig{office}},
+		{Ifname: "wl1", ScanResults: []fake.BSSConfig{guest}},
+		{Ifname: "wl2", ScanFails: true},
+	}})
+	f.run_all([]cmdTest{
+		{args: []string{"scan", "--results", "lo", "wl1"}, stdout: []string{"Iface", "lo               Office", "wl1              Guest"}},
+		{args: []string{"-o", "json", "scan", "--results", "lo", "wl1"}, stdout: []string{`"ifname": "wl1"`}},
+		{args: []string{"scan", "--results", "lo", "wl2"}, code: exitScanFailed, stdout: []string{"Trigger scan on interface lo, wl2"}},
+		{args: []string{"scan", "lo", "wl9"}, code: exitInterfaceUnknown, stdout: nil},
+	})
+}
+
+func TestScanResults(t *testing.T) {
+	dir := t.TempDir()
+	before, after := filepath.Join(dir, "before.json"), filepath.Join(dir, "after.json")
+	f := start_fake(t, fake.Scenario{Interfaces: []fake.InterfaceScenario{{Ifname: "lo", BSSs: []fake.BSSConfig{office, guest}}}})
+	f.run_all([]cmdTest{
+		{args: []string{"scan-results", "lo"}, stdout: []string{"Office", "Guest"}},
+		{args: []string{"scan-results", "--long", "lo"}, stdout: []string{"Gen Width NSS Load"}},
+		{args: []string{"scan-results", "--security", "wpa2", "--save", before, "lo"}, stdout: []string{"Office"}},
+		{args: []string{"-o", "yaml", "scan-results", "--band", "5", "lo"}, stdout: []string{"kind: scan-results", "ssid: Guest"}},
+		{args: []string{"scan-results", "--band", "7", "lo"}, code: exitUsage, stdout: nil},
+		{args: []string{"bss", "lo", "00:11:22:33:44:55"}, stdout: []string{"BSS 00:11:22:33:44:55", "ssid             Office", "security         wpa2"}},
+		{args: []string{"bss", "lo", "00:11:22:33:44:99"}, code: exitFailure, stdout: nil},
+		{args: []string{"bss", "lo", "nonsense"}, code: exitUsage, stdout: nil},
+	})
+	f.s.Interface("lo").AddBSS(lab)
+	f.run_all([]cmdTest{
+		{args: []string{"scan-results", "--save", after, "lo"}, stdout: []string{"Lab"}},
+		{args: []string{"scan", "diff", before, after}, stdout: []string{"Lab", "Guest"}},
+		{args: []string{"scan", "diff", before}, code: exitUsage, stdout: nil},
+		{args: []string{"--bus", "unix:path=" + filepath.Join(dir, "nobus"), "scan", "diff", before, after}, stdout: []string{"Lab"}},
+		{args: []string{"--bus", "unix:path=" + filepath.Join(dir, "nobus"), "scan-results", "lo"}, code: exitNoSupplicant, stdout: nil},
+		{args: []string{"--bus", "unix:path=" + filepath.Join(dir, "nobus"), "scan", "--help"}, stdout: []string{"scan - search for wlan networks"}},
+		{args: []string{"--bus", "unix:path=" + filepath.Join(dir, "nobus"), "networks", "add", "-h"}, stdout: []string{"--ssid"}},
+		{args: []string{"--bus", "unix:path=" + filepath.Join(dir, "nobus"), "help", "scan"}, stdout: []string{"scan - search for wlan networks"}},
+	})
+}
+
+func TestScanWatch(t *testing.T) {
+	f := start_fake(t, fake.Scenario{Interfaces: []fake.InterfaceScenario{{Ifname: "lo", BSSs: []fake.BSSConfig{office}}}})
+	iface := f.s.Interface("lo")
+	r := f.run_interrupted(func() { iface.AddBSS(lab) }, "-o", "json", "scan", "watch", "--interval", "0", "lo")
+	if r.code != 0 || !strings.Contains(r.stdout, `"kind": "bss-event"`) || !strings.Contains(r.stdout, "00:11:22:33:44:77") {
+		t.Errorf("scan watch: exit code %d, output\n%s\n%s", r.code, r.stdout, r.stderr)
+	}
+	r = f.run_interrupted(nil, "scan", "watch", "--interval", "100ms", "lo")
+	if r.code != 0 || !strings.Contains(r.stdout, "Scan watch on lo") {
+		t.Errorf("scan watch: exit code %d, output\n%s\n%s", r.code, r.stdout, r.stderr)
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "import.conf")
+	err := ioutil.WriteFile(conf, []byte(`# imported
+ctrl_interface=DIR=/run/wpa_supplicant
+network={
+	ssid="Home # net"   # comment
+	psk="pass word"
+	key_mgmt=WPA-PSK
+	id_str=P"home\"#1"   # escaped quote
+	dot11RSNAConfigPMKLifetime=43200
+}
+blob-base64-ca2={
+aGVsbG8=
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := filepath.Join(dir, "groups.conf")
+	if err := ioutil.WriteFile(groups, []byte("network={\n\tssid=\"Lab\"\n\tsae_groups=19 20\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	password := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(password, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := start_fake(t, office_scenario(""))
+	f.run_all([]cmdTest{
+		{args: []string{"networks", "list", "lo"}, stdout: []string{` 0 "Office"`}},
+		{args: []string{"networks", "show", "--id", "0", "lo"}, stdout: []string{"Enabled                      true", "psk                          <hidden>"}},
+		{args: []string{"networks", "show", "--id", "0", "--show-secrets", "lo"}, stdout: []string{`psk                          "secret"`}},
+		{args: []string{"networks", "show", "--id", "7", "lo"}, code: exitNetworkUnknown, stdout: nil},
+		{args: []string{"networks", "add", "--ssid", "Home", "--psk", "password1", "--set", "scan_ssid=1", "lo"}, stdout: []string{"1"}},
+		{args: []string{"networks", "add", "--ssid", "Lab", "--set", "bogus=1", "lo"}, code: exitUsage, stdout: nil},
+		{args: []string{"networks", "add", "--ssid", "Lab", "--key_mgmt", "WPA-EAP", "--set-file", "password=" + password, "lo"}, stdout: []string{"2"}},
+		{args: []string{"networks", "enable", "--id", "1", "lo"}},
+		{args: []string{"networks", "disable", "--id", "1", "lo"}},
+		{args: []string{"networks", "set", "--id", "1", "--prio", "3", "lo"}, stdout: []string{"Network 1: changed priority"}},
+		{args: []string{"networks", "set", "--id", "1", "--prio", "3", "lo"}, stdout: []string{"Network 1 unchanged"}},
+		{args: []string{"-o", "json", "networks", "set", "--id", "1", "--prio", "4", "--reassociate", "lo"}, stdout: []string{`"kind": "network-set"`, `"priority"`, `"reassociated": false`}},
+		{args: []string{"-o", "json", "networks", "set", "--id", "1", "--prio", "4", "lo"}, stdout: []string{`"kind": "network-set"`, `"changed": []`}},
+		{args: []string{"-o", "json", "networks", "show", "--id", "1", "lo"}, stdout: []string{`"priority": "4"`}},
+		{args: []string{"networks", "select", "--id", "0", "lo"}},
+		{args: []string{"networks", "remove", "--id", "2", "lo"}},
+		{args: []string{"networks", "remove", "--id", "2", "lo"}, code: exitNetworkUnknown, stdout: nil},
+		{args: []string{"networks", "export", "lo"}, stdout: []string{"# lo has no ctrl_interface\n", "network={\n\tssid=\"Office\"", "\t#psk=<hidden>", "blob-base64-ca={\naGVsbG8=\n}"}},
+		{args: []string{"networks", "import", "--dry-run", "--file", conf, "lo"}, stdout: []string{"Would add blob ca2, 5 bytes", `Would add network: ssid="Home # net" psk=<hidden> key_mgmt=WPA-PSK`}},
+		{args: []string{"networks", "import", "--file", conf, "lo"}, stdout: []string{"Added blob ca2, 5 bytes", `Added network 3: ssid="Home # net"`, "dot11RSNAConfigPMKLifetime=43200"}},
+		{args: []string{"networks", "import", "--file", conf, "lo"}, stdout: []string{"Blob ca2 unchanged", `Added network 4: ssid="Home # net"`}},
+		{args: []string{"networks", "import", "lo"}, code: exitUsage, stdout: nil},
+		{args: []string{"networks", "import", "--file", groups, "lo"}, code: exitUsage, stdout: nil, stderr: []string{"groups.conf:3: Value of network field ´sae_groups´"}},
+	})
+	iface := f.s.Interface("lo")
+	fields := map[string]map[string]string{}
+	for _, n := range iface.Networks() {
+		fields[string(n.Path())] = n.Fields()
+	}
+	home := fields[string(iface.Path())+"/Networks/1"]
+	if home["ssid"] != `"Home"` || home["priority"] != "4" || home["scan_ssid"] != "1" || home["disabled"] != "1" {
+		t.Errorf("network 1 has fields %v", home)
+	}
+	if _, ok := fields[string(iface.Path())+"/Networks/2"]; ok {
+		t.Error("network 2 was not removed")
+	}
+	if imported := fields[string(iface.Path())+"/Networks/3"]; imported["ssid"] != `"Home # net"` || imported["psk"] != `"pass word"` || imported["id_str"] != hex.EncodeToString([]byte(`home"#1`)) || imported["dot11RSNAConfigPMKLifetime"] != "43200" || imported["disabled"] != "0" {
+		t.Errorf("imported network has fields %v", imported)
+	}
+	if string(iface.Blobs()["ca2"]) != "hello" {
+		t.Errorf("imported blobs %v", iface.Blobs())
+	}
+	if r := f.run("networks", "export", "lo"); strings.Contains(r.stdout, "update_config") {
+		t.Errorf("networks export writes update_config:\n%s", r.stdout)
+	}
+	f.run_all([]cmdTest{
+		{args: []string{"networks", "remove", "--all", "lo"}},
+		{args: []string{"networks", "list", "lo"}, stdout: []string{"Id SSID"}},
+	})
+	if n := len(iface.Networks()); n != 0 {
+		t.Errorf("%d networks left after remove --all", n)
+	}
+}
+
+func TestNetworkImportRollback(t *testing.T) {
+	conf := filepath.Join(t.TempDir(), "import.conf")
+	err := ioutil.WriteFile(conf, []byte(`network={
+	ssid="Home"
+}
+blob-base64-ca2={
+aGVsbG8=
+}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := office_scenario("")
+	sc.Interfaces[0].Fail = map[string]string{"AddNetwork": fake.ErrInvalidArgs}
+	f := start_fake(t, sc)
+	f.run_all([]cmdTest{
+		{args: []string{"networks", "import", "--file", conf, "lo"}, code: exitInvalidArgs, stdout: nil, stderr: []string{"lo: network of line 1: invalid argument"}},
+	})
+	if blobs := f.s.Interface("lo").Blobs(); len(blobs) != 1 {
+		t.Errorf("blobs %v left after a failed import", blobs)
+	}
+	if err := ioutil.WriteFile(conf, []byte("blob-base64-ca={\nd29ybGQ=\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f.run_all([]cmdTest{
+		{args: []string{"networks", "import", "--file", conf, "lo"}, code: exitFailure, stdout: nil, stderr: []string{"lo: blob ca: blob already exists with another content"}},
+	})
+	if blobs := f.s.Interface("lo").Blobs(); string(blobs["ca"]) != "hello" {
+		t.Errorf("blob ca replaced by %q", blobs["ca"])
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	dir := t.TempDir()
+	data, out := filepath.Join(dir, "data"), filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(data, []byte("certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := start_fake(t, office_scenario(""))
+	f.run_all([]cmdTest{
+		{args: []string{"blob", "list", "lo"}, stdout: []string{"ca                               5"}},
+		{args: []string{"blob", "add", "--name", "cert", "--data", data, "lo"}},
+		{args: []string{"blob", "add", "--name", "cert", "--data", data, "lo"}, code: exitFailure},
+		{args: []string{"blob", "list", "lo"}, stdout: []string{"cert                             11"}},
+		{args: []string{"blob", "list", "--no-legend", "lo"}, stdout: []string{"ca\ncert\n"}},
+		{args: []string{"blob", "get", "--name", "cert", "--output", out, "lo"}},
+		{args: []string{"blob", "remove", "--name", "cert", "lo"}},
+		{args: []string{"blob", "remove", "--name", "cert", "lo"}, code: exitBlobUnknown},
+		{args: []string{"blob", "get", "--name", "cert", "--output", out, "lo"}, code: exitBlobUnknown},
+	})
+	if content, err := ioutil.ReadFile(out); err != nil || string(content) != "certificate" {
+		t.Errorf("blob get wrote %q, %v", content, err)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	f := start_fake(t, office_scenario(""))
+	iface := f.s.Interface("lo")
+	r := f.run_interrupted(func() { iface.SetState("scanning") }, "monitor")
+	if r.code != 0 || !strings.Contains(r.stderr, "PropertiesChanged") {
+		t.Errorf("monitor: exit code %d, output\n%s", r.code, r.stderr)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	f := start_fake(t, fake.Scenario{
+		Interfaces: []fake.InterfaceScenario{{Ifname: "lo", Fail: map[string]string{"Reconnect": fake.ErrAccessDenied}}},
+		Fail:       map[string]string{"CreateInterface": fake.ErrInvalidArgs},
+	})
+	f.run_all([]cmdTest{
+		{args: []string{"-o", "xml", "interface", "list"}, code: exitUsage, stdout: nil},
+		{args: []string{"--bus", "unix:path=/nonexistent", "interface", "list"}, code: exitNoSupplicant, stdout: nil},
+		{args: []string{"reconnect", "lo"}, code: exitAccessDenied, stdout: nil},
+		{args: []string{"up", "wl0"}, code: exitInvalidArgs, stdout: nil},
+		{args: []string{"status", "wl9"}, code: exitInterfaceUnknown, stdout: nil},
+	})
+}
+
+func TestCommandPath(t *testing.T) {
+	commands := []*cli.Command{
+		{Name: "scan", Aliases: []string{"sc"}, Subcommands: []*cli.Command{{Name: "diff"}, {Name: "abort"}}},
+		{Name: "help", Aliases: []string{"h"}},
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"scan", "wlan0"}, "scan"},
+		{[]string{"sc", "diff", "a.json", "b.json"}, "scan diff"},
+		{[]string{"scan", "abort", "diff"}, "scan abort"},
+		{[]string{"h"}, "help"},
+		{[]string{"wlan0", "scan"}, ""},
+	}
+	for _, tt := range tests {
+		if got := command_path(commands, tt.args); got != tt.want {
+			t.Errorf("command_path(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// fieldKind tells how the value of a network field is passed to
+// AddNetwork
+type fieldKind int
+
+const (
+	// fieldString is text, which wpa_supplicant encloses in quotes
+	fieldString fieldKind = iota
+	// fieldRaw is an enum or a list, which wpa_supplicant takes verbatim
+	fieldRaw
+	// fieldInt is an integer, sent as int32
+	fieldInt
+)
+
+// network_fields are the known fields of a network block of
+// wpa_supplicant.conf. The raw fields are those wpa_supplicant does not
+// quote when they are given as string.
+var network_fields = map[string]fieldKind{
+	"ssid":                  fieldString,
+	"psk":                   fieldString,
+	"sae_password":          fieldString,
+	"sae_password_id":       fieldString,
+	"password":              fieldString,
+	"identity":              fieldString,
+	"anonymous_identity":    fieldString,
+	"ca_cert":               fieldString,
+	"ca_path":               fieldString,
+	"client_cert":           fieldString,
+	"private_key":           fieldString,
+	"private_key_passwd":    fieldString,
+	"ca_cert2":              fieldString,
+	"ca_path2":              fieldString,
+	"client_cert2":          fieldString,
+	"private_key2":          fieldString,
+	"private_key2_passwd":   fieldString,
+	"dh_file":               fieldString,
+	"subject_match":         fieldString,
+	"altsubject_match":      fieldString,
+	"domain_suffix_match":   fieldString,
+	"domain_match":          fieldString,
+	"check_cert_subject":    fieldString,
+	"phase1":                fieldString,
+	"phase2":                fieldString,
+	"pac_file":              fieldString,
+	"openssl_ciphers":       fieldString,
+	"pin":                   fieldString,
+	"engine_id":             fieldString,
+	"key_id":                fieldString,
+	"cert_id":               fieldString,
+	"ca_cert_id":            fieldString,
+	"wep_key0":              fieldString,
+	"wep_key1":              fieldString,
+	"wep_key2":              fieldString,
+	"wep_key3":              fieldString,
+	"bgscan":                fieldString,
+	"id_str":                fieldString,
+	"key_mgmt":              fieldRaw,
+	"proto":                 fieldRaw,
+	"pairwise":              fieldRaw,
+	"group":                 fieldRaw,
+	"group_mgmt":            fieldRaw,
+	"auth_alg":              fieldRaw,
+	"eap":                   fieldRaw,
+	"bssid":                 fieldRaw,
+	"bssid_hint":            fieldRaw,
+	"bssid_ignore":          fieldRaw,
+	"bssid_accept":          fieldRaw,
+	"scan_freq":             fieldRaw,
+	"freq_list":             fieldRaw,
+	"priority":              fieldInt,
+	"disabled":              fieldInt,
+	"mode":                  fieldInt,
+	"frequency":             fieldInt,
+	"scan_ssid":             fieldInt,
+	"ignore_broadcast_ssid": fieldInt,
+	"ieee80211w":            fieldInt,
+	"sae_pwe":               fieldInt,
+	"sae_pk":                fieldInt,
+	"mac_addr":              fieldInt,
+	"ocv":                   fieldInt,
+	"beacon_prot":           fieldInt,
+	"transition_disable":    fieldInt,
+	"proactive_key_caching": fieldInt,
+	"ft_eap_pmksa_caching":  fieldInt,
+	"eapol_flags":           fieldInt,
+	"eap_workaround":        fieldInt,
+	"fragment_size":         fieldInt,
+	"ocsp":                  fieldInt,
+	"erp":                   fieldInt,
+	"sim_num":               fieldInt,
+	"wep_tx_keyidx":         fieldInt,
+	"wpa_ptk_rekey":         fieldInt,
+	"group_rekey":           fieldInt,
+	"owe_group":             fieldInt,
+	"owe_only":              fieldInt,
+	"fils_dh_group":         fieldInt,
+	"multi_ap_backhaul_sta": fieldInt,
+	"wps_disabled":          fieldInt,
+	"beacon_int":            fieldInt,
+	"dtim_period":           fieldInt,
+	"ht":                    fieldInt,
+	"vht":                   fieldInt,
+	"he":                    fieldInt,
+	"disable_ht":            fieldInt,
+	"disable_ht40":          fieldInt,
+	"disable_vht":           fieldInt,
+	"disable_he":            fieldInt,
+	"disable_sgi":           fieldInt,
+	"disable_ldpc":          fieldInt,
+	"max_oper_chwidth":      fieldInt,
+}
+
+// secret_fields are the network fields masked unless ´--show-secrets´ is
+// given
+var secret_fields = map[string]bool{
+	"psk":                 true,
+	"password":            true,
+	"sae_password":        true,
+	"private_key_passwd":  true,
+	"private_key2_passwd": true,
+	"wep_key0":            true,
+	"wep_key1":            true,
+	"wep_key2":            true,
+	"wep_key3":            true,
+	"pin":                 true,
+}
+
+// secretMask replaces the value of a secret field
+const secretMask = "<hidden>"
+
+// network_field_value converts the text of a known network field to the
+// value for AddNetwork. A psk of 64 hex digits is the raw key, not a
+// passphrase, and is sent as byte array.
+func network_field_value(key, text string) (interface{}, error) {
+	kind, ok := network_fields[key]
+	if !ok {
+		return nil, usageError{fmt.Sprintf("Unknown network field ´%s´", key)}
+	}
+	switch kind {
+	case fieldInt:
+		n, err := strconv.ParseInt(text, 0, 32)
+		if err != nil {
+			return nil, usageError{fmt.Sprintf("Invalid value ´%s´ of network field ´%s´, expected an integer", text, key)}
+		}
+		return int32(n), nil
+	case fieldRaw:
+		if len(text) == 0 {
+			return nil, usageError{fmt.Sprintf("Empty value of network field ´%s´", key)}
+		}
+		return text, nil
+	}
+	if key == "psk" && len(text) == 64 {
+		if raw, err := hex.DecodeString(text); err == nil {
+			return raw, nil
+		}
+	}
+	if len(text) == 0 {
+		/* wpa_supplicant rejects empty strings */
+		return nil, usageError{fmt.Sprintf("Empty value of network field ´%s´", key)}
+	}
+	return text, nil
+}
+
+// conf_value renders a value built by network_field_value in
+// wpa_supplicant.conf syntax, as wpa_supplicant stores it
+func conf_value(key string, v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return hex.EncodeToString(v)
+	case int32:
+		return strconv.Itoa(int(v))
+	case string:
+		if network_fields[key] == fieldString {
+			return `"` + v + `"`
+		}
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// conf_field_value converts a value in wpa_supplicant.conf syntax to the
+// value for AddNetwork. Quoted strings are sent as text, P"..." strings
+// with escapes as byte array, since their text may hold any byte and
+// wpa_supplicant would quote it again. Unquoted values of string fields
+// are hex. Fields missing from network_fields take quoted strings and
+// integers only: wpa_supplicant quotes every other string it is given.
+func conf_field_value(key, value string) (interface{}, error) {
+	kind, known := network_fields[key]
+	quoted := len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)
+	text := strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	if strings.HasPrefix(value, `P"`) && strings.HasSuffix(value, `"`) && len(value) >= 3 {
+		text, err := strconv.Unquote(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid escape in value of network field ´%s´", key)
+		}
+		if known && kind != fieldString {
+			return nil, fmt.Errorf("Network field ´%s´ must not be quoted", key)
+		}
+		if len(text) == 0 {
+			return nil, fmt.Errorf("Empty value of network field ´%s´", key)
+		}
+		return []byte(text), nil
+	}
+	switch {
+	case quoted:
+		if known && kind != fieldString {
+			return nil, fmt.Errorf("Network field ´%s´ must not be quoted", key)
+		}
+		if len(text) == 0 {
+			return nil, fmt.Errorf("Empty value of network field ´%s´", key)
+		}
+		return text, nil
+	case !known:
+		if n, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return int32(n), nil
+		}
+		if len(value) == 0 {
+			return nil, fmt.Errorf("Empty value of network field ´%s´", key)
+		}
+		return nil, fmt.Errorf("Value of network field ´%s´, which is unknown to wpactl, is neither quoted nor an integer", key)
+	case kind == fieldString:
+		raw, err := hex.DecodeString(value)
+		if err != nil || len(raw) == 0 {
+			return nil, fmt.Errorf("Value of network field ´%s´ is neither quoted nor hex", key)
+		}
+		return raw, nil
+	}
+	return network_field_value(key, value)
+}
+
+// parse_field_assignments parses the values of ´--set key=value´ and
+// ´--set-file key=path´ into AddNetwork arguments. The content of a file
+// is taken without its trailing line break.
+func parse_field_assignments(sets, files []string) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	add := func(option, assignment string, from_file bool) error {
+		eq := strings.IndexByte(assignment, '=')
+		if eq <= 0 {
+			return usageError{fmt.Sprintf("Invalid %s ´%s´, expected key=value", option, assignment)}
+		}
+		key, text := assignment[:eq], assignment[eq+1:]
+		if _, dup := args[key]; dup {
+			return usageError{fmt.Sprintf("Network field ´%s´ given twice", key)}
+		}
+		if from_file {
+			content, err := ioutil.ReadFile(text)
+			if err != nil {
+				return err
+			}
+			text = strings.TrimRight(string(content), "\r\n")
+		}
+		v, err := network_field_value(key, text)
+		if err != nil {
+			return err
+		}
+		args[key] = v
+		return nil
+	}
+	for _, s := range sets {
+		if err := add("--set", s, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		if err := add("--set-file", f, true); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNetworkFieldValue(t *testing.T) {
+	raw_psk := strings.Repeat("0123456789abcdef", 4)
+	tests := []struct {
+		key     string
+		text    string
+		want    interface{}
+		invalid bool
+	}{
+		{key: "ssid", text: "Office", want: "Office"},
+		{key: "ssid", text: "My Office ´x´", want: "My Office ´x´"},
+		{key: "ssid", text: "4f6666696365", want: "4f6666696365"},
+		{key: "psk", text: "secret passphrase", want: "secret passphrase"},
+		{key: "psk", text: raw_psk, want: bytes.Repeat([]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}, 4)},
+		{key: "psk", text: strings.Repeat("x", 64), want: strings.Repeat("x", 64)},
+		{key: "password", text: raw_psk, want: raw_psk},
+		{key: "key_mgmt", text: "WPA-PSK SAE", want: "WPA-PSK SAE"},
+		{key: "freq_list", text: "2412 5180", want: "2412 5180"},
+		{key: "bssid", text: "00:11:22:33:44:55", want: "00:11:22:33:44:55"},
+		{key: "priority", text: "7", want: int32(7)},
+		{key: "priority", text: "-1", want: int32(-1)},
+		{key: "eapol_flags", text: "0x3", want: int32(3)},
+		{key: "ssid", text: "", invalid: true},
+		{key: "key_mgmt", text: "", invalid: true},
+		{key: "priority", text: "high", invalid: true},
+		{key: "priority", text: "", invalid: true},
+		{key: "priority", text: "4294967296", invalid: true},
+		{key: "no_such_field", text: "1", invalid: true},
+	}
+	for _, tt := range tests {
+		v, err := network_field_value(tt.key, tt.text)
+		if tt.invalid {
+			if _, ok := err.(usageError); !ok {
+				t.Errorf("network_field_value(%q, %q) = %v, %v, want a usage error", tt.key, tt.text, v, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("network_field_value(%q, %q) = %#v, %v, want %#v", tt.key, tt.text, v, err, tt.want)
+		}
+	}
+}
+
+func TestConfFieldValue(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    interface{}
+		invalid bool
+	}{
+		{key: "ssid", value: `"Office"`, want: "Office"},
+		{key: "ssid", value: `"Home # net"`, want: "Home # net"},
+		{key: "ssid", value: "4f6666696365", want: []byte("Office")},
+		{key: "ssid", value: `P"Caf\xc3\xa9\n"`, want: []byte("Café\n")},
+		{key: "id_str", value: `P"a\"#b"`, want: []byte(`a"#b`)},
+		{key: "psk", value: `"pass word"`, want: "pass word"},
+		{key: "psk", value: strings.Repeat("ab", 32), want: bytes.Repeat([]byte{0xab}, 32)},
+		{key: "key_mgmt", value: "WPA-PSK SAE", want: "WPA-PSK SAE"},
+		{key: "priority", value: "5", want: int32(5)},
+		{key: "sae_groups", value: `"19 20"`, want: "19 20"},
+		{key: "dot11RSNAConfigPMKLifetime", value: "43200", want: int32(43200)},
+		{key: "ssid", value: "Office", invalid: true},
+		{key: "ssid", value: `""`, invalid: true},
+		{key: "ssid", value: `"`, invalid: true},
+		{key: "ssid", value: `P"\q"`, invalid: true},
+		{key: "key_mgmt", value: `"WPA-PSK"`, invalid: true},
+		{key: "priority", value: `"5"`, invalid: true},
+		{key: "priority", value: "high", invalid: true},
+		{key: "key_mgmt", value: `P"WPA-PSK"`, invalid: true},
+		{key: "sae_groups", value: "19 20", invalid: true},
+		{key: "sae_groups", value: "", invalid: true},
+	}
+	for _, tt := range tests {
+		v, err := conf_field_value(tt.key, tt.value)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("conf_field_value(%q, %q) = %#v, want an error", tt.key, tt.value, v)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("conf_field_value(%q, %q) = %#v, %v, want %#v", tt.key, tt.value, v, err, tt.want)
+		}
+	}
+}
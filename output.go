@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/godbus/dbus/v5"
+	"gopkg.in/yaml.v3"
+	"jp.net/wpactl/supplicant"
+)
+
+// outputSchemaVersion is the version of the JSON/YAML documents. It is
+// incremented whenever a field is renamed, removed or changes its type.
+const outputSchemaVersion = 1
+
+var outputFormats = []string{"text", "json", "yaml"}
+
+// outputDocument is the envelope of every JSON/YAML document
+type outputDocument struct {
+	Kind    string      `json:"kind" yaml:"kind"`
+	Version int         `json:"version" yaml:"version"`
+	Data    interface{} `json:"data" yaml:"data"`
+}
+
+type interfaceOutput struct {
+	Ifname string          `json:"ifname" yaml:"ifname"`
+	State  string          `json:"state" yaml:"state"`
+	Path   dbus.ObjectPath `json:"path" yaml:"path"`
+}
+
+type securityOutput struct {
+	KeyMgmt   []string `json:"key_mgmt" yaml:"key_mgmt"`
+	Pairwise  []string `json:"pairwise" yaml:"pairwise"`
+	Group     string   `json:"group,omitempty" yaml:"group,omitempty"`
+	MgmtGroup string   `json:"mgmt_group,omitempty" yaml:"mgmt_group,omitempty"`
+}
+
+type bssOutput struct {
+	// Ifname is the interface which found the BSS, set in scan results
+	Ifname    string          `json:"ifname,omitempty" yaml:"ifname,omitempty"`
+	Path      dbus.ObjectPath `json:"path" yaml:"path"`
+	SSID      *string         `json:"ssid" yaml:"ssid"`
+	SSIDHex   string          `json:"ssid_hex" yaml:"ssid_hex"`
+	BSSID     string          `json:"bssid" yaml:"bssid"`
+	Mode      string          `json:"mode" yaml:"mode"`
+	Frequency uint16          `json:"frequency" yaml:"frequency"`
+	Signal    int16           `json:"signal" yaml:"signal"`
+	Age       uint32          `json:"age" yaml:"age"`
+	Privacy   bool            `json:"privacy" yaml:"privacy"`
+	WPA       *securityOutput `json:"wpa" yaml:"wpa"`
+	RSN       *securityOutput `json:"rsn" yaml:"rsn"`
+	Security  []string        `json:"security" yaml:"security"`
+	Elements  *elementsOutput `json:"elements" yaml:"elements"`
+}
+
+type bssLoadOutput struct {
+	StationCount uint16 `json:"station_count" yaml:"station_count"`
+	// ChannelUtilization in percent
+	ChannelUtilization float64 `json:"channel_utilization" yaml:"channel_utilization"`
+	AdmissionCapacity  uint16  `json:"admission_capacity" yaml:"admission_capacity"`
+}
+
+type mobilityDomainOutput struct {
+	ID              string `json:"id" yaml:"id"`
+	OverDS          bool   `json:"over_ds" yaml:"over_ds"`
+	ResourceRequest bool   `json:"resource_request" yaml:"resource_request"`
+}
+
+type rmCapabilitiesOutput struct {
+	LinkMeasurement bool `json:"link_measurement" yaml:"link_measurement"`
+	NeighborReport  bool `json:"neighbor_report" yaml:"neighbor_report"`
+	BeaconPassive   bool `json:"beacon_passive" yaml:"beacon_passive"`
+	BeaconActive    bool `json:"beacon_active" yaml:"beacon_active"`
+	BeaconTable     bool `json:"beacon_table" yaml:"beacon_table"`
+}
+
+type elementsOutput struct {
+	Generation     string                `json:"generation" yaml:"generation"`
+	ChannelWidth   int                   `json:"channel_width" yaml:"channel_width"`
+	SpatialStreams int                   `json:"spatial_streams" yaml:"spatial_streams"`
+	MaxRate        float64               `json:"max_rate" yaml:"max_rate"`
+	Country        string                `json:"country" yaml:"country"`
+	BSSLoad        *bssLoadOutput        `json:"bss_load" yaml:"bss_load"`
+	MobilityDomain *mobilityDomainOutput `json:"mobility_domain" yaml:"mobility_domain"`
+	RMCapabilities *rmCapabilitiesOutput `json:"rm_capabilities" yaml:"rm_capabilities"`
+	BSSTransition  bool                  `json:"bss_transition" yaml:"bss_transition"`
+}
+
+type statusOutput struct {
+	Ifname    string          `json:"ifname" yaml:"ifname"`
+	Path      dbus.ObjectPath `json:"path" yaml:"path"`
+	State     string          `json:"state" yaml:"state"`
+	AuthMode  string          `json:"auth_mode" yaml:"auth_mode"`
+	BSS       *bssOutput      `json:"bss" yaml:"bss"`
+	Addresses []string        `json:"addresses" yaml:"addresses"`
+}
+
+type networkOutput struct {
+	ID       int             `json:"id" yaml:"id"`
+	SSID     string          `json:"ssid" yaml:"ssid"`
+	Priority int             `json:"priority" yaml:"priority"`
+	Disabled bool            `json:"disabled" yaml:"disabled"`
+	Path     dbus.ObjectPath `json:"path" yaml:"path"`
+}
+
+type networkShowOutput struct {
+	ID      int               `json:"id" yaml:"id"`
+	Path    dbus.ObjectPath   `json:"path" yaml:"path"`
+	Enabled bool              `json:"enabled" yaml:"enabled"`
+	Fields  map[string]string `json:"fields" yaml:"fields"`
+}
+
+type importedNetworkOutput struct {
+	ID     int               `json:"id" yaml:"id"`
+	Path   dbus.ObjectPath   `json:"path" yaml:"path"`
+	Fields map[string]string `json:"fields" yaml:"fields"`
+}
+
+type networkImportOutput struct {
+	DryRun   bool                    `json:"dry_run" yaml:"dry_run"`
+	Blobs    []blobOutput            `json:"blobs" yaml:"blobs"`
+	Networks []importedNetworkOutput `json:"networks" yaml:"networks"`
+}
+
+type networkAddOutput struct {
+	ID   int             `json:"id" yaml:"id"`
+	Path dbus.ObjectPath `json:"path" yaml:"path"`
+}
+
+type networkSetOutput struct {
+	ID           int             `json:"id" yaml:"id"`
+	Path         dbus.ObjectPath `json:"path" yaml:"path"`
+	Changed      []string        `json:"changed" yaml:"changed"`
+	Reassociated bool            `json:"reassociated" yaml:"reassociated"`
+}
+
+type blobOutput struct {
+	Name   string `json:"name" yaml:"name"`
+	Length int    `json:"length" yaml:"length"`
+}
+
+// printable_ssid returns the SSID as text if it consists of printable
+// UTF-8 characters only
+func printable_ssid(ssid []byte) (string, bool) {
+	if !utf8.Valid(ssid) {
+		return "", false
+	}
+	for _, r := range string(ssid) {
+		if !unicode.IsPrint(r) {
+			return "", false
+		}
+	}
+	return string(ssid), true
+}
+
+func new_security_output(sec supplicant.Security) *securityOutput {
+	if len(sec.KeyMgmt) == 0 {
+		return nil
+	}
+	return &securityOutput{
+		KeyMgmt:   sec.KeyMgmt,
+		Pairwise:  sec.Pairwise,
+		Group:     sec.Group,
+		MgmtGroup: sec.MgmtGroup,
+	}
+}
+
+// channel_utilization converts the utilization of the BSS Load element to
+// percent
+func channel_utilization(load *supplicant.BSSLoad) float64 {
+	return math.Round(float64(load.ChannelUtilization)*1000/255) / 10
+}
+
+func new_elements_output(ie *supplicant.ElementInfo) *elementsOutput {
+	out := &elementsOutput{
+		Generation:     ie.Generation,
+		ChannelWidth:   ie.ChannelWidth,
+		SpatialStreams: ie.SpatialStreams,
+		MaxRate:        ie.MaxRate,
+		Country:        ie.Country,
+		BSSTransition:  ie.BSSTransition,
+	}
+	if load := ie.BSSLoad; load != nil {
+		out.BSSLoad = &bssLoadOutput{
+			StationCount:       load.StationCount,
+			ChannelUtilization: channel_utilization(load),
+			AdmissionCapacity:  load.AdmissionCapacity,
+		}
+	}
+	if md := ie.MobilityDomain; md != nil {
+		out.MobilityDomain = &mobilityDomainOutput{
+			/* in octet order like the mobility_domain of hostapd */
+			ID:              fmt.Sprintf("%02x%02x", md.ID&0xff, md.ID>>8),
+			OverDS:          md.OverDS,
+			ResourceRequest: md.ResourceRequestProtocol,
+		}
+	}
+	if rm := ie.RMCapabilities; rm != nil {
+		out.RMCapabilities = &rmCapabilitiesOutput{
+			LinkMeasurement: rm.LinkMeasurement,
+			NeighborReport:  rm.NeighborReport,
+			BeaconPassive:   rm.BeaconPassive,
+			BeaconActive:    rm.BeaconActive,
+			BeaconTable:     rm.BeaconTable,
+		}
+	}
+	return out
+}
+
+func new_bss_output(info *supplicant.BSSInfo) *bssOutput {
+	out := &bssOutput{
+		Path:      info.Path,
+		SSIDHex:   hex.EncodeToString(info.SSID),
+		BSSID:     info.BSSID.String(),
+		Mode:      info.Mode,
+		Frequency: info.Frequency,
+		Signal:    info.Signal,
+		Age:       info.Age,
+		Privacy:   info.Privacy,
+		WPA:       new_security_output(info.WPA),
+		RSN:       new_security_output(info.RSN),
+		Security:  bss_security(info),
+		Elements:  new_elements_output(info.Elements()),
+	}
+	if ssid, ok := printable_ssid(info.SSID); ok {
+		out.SSID = &ssid
+	}
+	return out
+}
+
+// conf_value_text decodes a network property in wpa_supplicant.conf
+// syntax, i.e. a quoted string or a hex string
+func conf_value_text(v string) string {
+	if len(v) >= 2 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+		return v[1 : len(v)-1]
+	}
+	if raw, err := hex.DecodeString(v); err == nil {
+		if text, ok := printable_ssid(raw); ok {
+			return text
+		}
+	}
+	return v
+}
+
+func new_network_output(id int, path dbus.ObjectPath, nprops map[string]dbus.Variant) networkOutput {
+	str := func(key string) string {
+		s, _ := nprops[key].Value().(string)
+		return s
+	}
+	out := networkOutput{ID: id, Path: path, Disabled: str("disabled") == "1"}
+	out.Priority, _ = strconv.Atoi(str("priority"))
+	if ssid := str("ssid"); len(ssid) > 0 {
+		out.SSID = conf_value_text(ssid)
+	} else {
+		out.SSID = str("bssid")
+	}
+	return out
+}
+
+// plain_value converts a D-Bus value for the output: variants are
+// unwrapped, byte arrays are hex encoded and maps get string keys
+func plain_value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case dbus.Variant:
+		return plain_value(v.Value())
+	case []byte:
+		return hex.EncodeToString(v)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = plain_value(rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Map:
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = plain_value(iter.Value().Interface())
+		}
+		return m
+	}
+	return v
+}
+
+// plain_value_text renders a value converted by plain_value on a single
+// line, lists and maps in JSON syntax
+func plain_value_text(v interface{}) string {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		text, _ := json.Marshal(v)
+		return string(text)
+	}
+	return fmt.Sprint(v)
+}
+
+func (ce *cliExtended) text_output() bool {
+	return ce.output == "text"
+}
+
+// print_output writes data as JSON or YAML document of the given kind. In
+// text mode the text function is called instead.
+func (ce *cliExtended) print_output(kind string, data interface{}, text func()) error {
+	doc := outputDocument{Kind: kind, Version: outputSchemaVersion, Data: data}
+	switch ce.output {
+	case "text":
+		text()
+		return nil
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case "yaml":
+		fmt.Println("---")
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("Unknown output format ´%s´, use one of %s", ce.output, strings.Join(outputFormats, ", "))
+	}
+}
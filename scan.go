@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"jp.net/wpactl/supplicant"
+)
+
+// ifaceScan is the scan result of one interface
+type ifaceScan struct {
+	ifname string
+	infos  []*supplicant.BSSInfo
+}
+
+// scan_args builds the arguments of the Scan method from the options of
+// ´scan´
+func (ce *cliExtended) scan_args() (supplicant.ScanArgs, error) {
+	allow_roam := ce.Bool("allow-roam")
+	scan_args := supplicant.ScanArgs{Type: ce.String("type"), AllowRoam: &allow_roam}
+	for _, ssid := range ce.StringSlice("ssid") {
+		scan_args.SSIDs = append(scan_args.SSIDs, []byte(ssid))
+	}
+	for _, ie := range ce.StringSlice("ie") {
+		raw, err := hex.DecodeString(strings.ReplaceAll(ie, ":", ""))
+		if err != nil || len(raw) < 2 {
+			return scan_args, usageError{fmt.Sprintf("Invalid information element ´%s´, expected hex bytes", ie)}
+		}
+		scan_args.IEs = append(scan_args.IEs, raw)
+	}
+	var err error
+	scan_args.Channels, err = scan_channels(ce.StringSlice("channel"), ce.StringSlice("band"))
+	return scan_args, err
+}
+
+// scan triggers a scan on each interface given as argument. With
+// ´--results´ the scans run in parallel and their results are printed
+// together once all interfaces signaled ScanDone.
+func (ce *cliExtended) scan() error {
+	if !ce.Args().Present() {
+		return usageError{"No interface name given"}
+	}
+	scan_args, err := ce.scan_args()
+	if err != nil {
+		return err
+	}
+	ifnames := ce.Args().Slice()
+	ifaces := make([]*supplicant.Interface, len(ifnames))
+	for n, ifname := range ifnames {
+		if ifaces[n], err = ce.GetInterface(ce.ctx(), ifname); err != nil {
+			return fmt.Errorf("%s: %w", ifname, err)
+		}
+	}
+	if ce.text_output() {
+		fmt.Println("Trigger scan on interface", strings.Join(ifnames, ", "))
+	}
+	if !ce.Bool("results") {
+		for n, iface := range ifaces {
+			if err := iface.Scan(ce.ctx(), scan_args); err != nil {
+				return fmt.Errorf("%s: %w", ifnames[n], err)
+			}
+		}
+		return nil
+	}
+	wctx, cancel := ce.wait_ctx()
+	defer cancel()
+	scans := make([]ifaceScan, len(ifaces))
+	errs := make([]error, len(ifaces))
+	var wg sync.WaitGroup
+	for n := range ifaces {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			scans[n].ifname = ifnames[n]
+			if err := ifaces[n].ScanAndWait(wctx, scan_args); err != nil {
+				errs[n] = err
+				return
+			}
+			scans[n].infos, errs[n] = ifaces[n].BSSInfos(ce.ctx())
+		}(n)
+	}
+	wg.Wait()
+	for n, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %w", ifnames[n], wait_error(err, ce.timeout, "waiting for scan results"))
+		}
+	}
+	return ce.print_scan_results(scans, nil)
+}
+
+// show_scan_results waits for a running scan and prints the BSSs selected
+// by filter, all if filter is nil
+func (ce *cliExtended) show_scan_results(filter *bssFilter) error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	wctx, cancel := ce.wait_ctx()
+	defer cancel()
+	if err := iface.WaitScan(wctx); err != nil {
+		return fmt.Errorf("%s: %w", ifname, wait_error(err, ce.timeout, "waiting for scan results"))
+	}
+	infos, err := iface.BSSInfos(ce.ctx())
+	if err != nil {
+		return err
+	}
+	return ce.print_scan_results([]ifaceScan{{ifname, infos}}, filter)
+}
+
+// print_scan_results merges the scan results of the interfaces and prints
+// the BSSs selected by filter, all if filter is nil. Each BSS is tagged
+// with its interface.
+func (ce *cliExtended) print_scan_results(scans []ifaceScan, filter *bssFilter) error {
+	var infos []*supplicant.BSSInfo
+	ifnames := make(map[dbus.ObjectPath]string)
+	for _, scan := range scans {
+		for _, info := range scan.infos {
+			ifnames[info.Path] = scan.ifname
+		}
+		infos = append(infos, scan.infos...)
+	}
+	if filter != nil {
+		infos = filter.apply(infos)
+	}
+	results := make([]*bssOutput, 0, len(infos))
+	for _, info := range infos {
+		out := new_bss_output(info)
+		out.Ifname = ifnames[info.Path]
+		results = append(results, out)
+	}
+	if filter != nil && len(filter.save) > 0 {
+		if err := save_snapshot(filter.save, results); err != nil {
+			return err
+		}
+	}
+	/* The interface column is only needed for several interfaces */
+	tagged := len(scans) > 1
+	return ce.print_output("scan-results", results, func() {
+		header := "SSID                             BSSID        Freq Sig Age Flags"
+		if ce.Bool("long") {
+			header = "SSID                             BSSID        Freq Sig Age Gen Width NSS Load Flags"
+		}
+		if tagged {
+			header = "Iface            " + header
+		}
+		fmt.Println(header)
+		fmt.Println(strings.Repeat("=", len(header)))
+		for idx, info := range infos {
+			if tagged {
+				fmt.Printf("%-16s ", results[idx].Ifname)
+			}
+			if ce.Bool("long") {
+				fmt.Printf("%-32s %02x %d %d %3v %s %v %v\n", info.SSID, []byte(info.BSSID), info.Frequency, info.Signal, info.Age, elements_columns(results[idx].Elements), info.RSN.KeyMgmt, info.RSN.Pairwise)
+			} else {
+				fmt.Printf("%-32s %02x %d %d %3v %v %v\n", info.SSID, []byte(info.BSSID), info.Frequency, info.Signal, info.Age, info.RSN.KeyMgmt, info.RSN.Pairwise)
+			}
+		}
+	})
+}
+
+func (ce *cliExtended) scan_abort() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	if err := iface.AbortScan(ce.ctx()); err != nil {
+		return fmt.Errorf("%s: %w", ifname, err)
+	}
+	fmt.Println("Aborted scan on interface", ifname)
+	return nil
+}
+
+// autoscan_arg builds the argument of AutoScan from the options of ´scan
+// auto´, e.g. ´exponential:3:300´. The empty string disables autoscan.
+func (ce *cliExtended) autoscan_arg() (string, error) {
+	mode := ce.String("mode")
+	if ce.Bool("off") {
+		for _, name := range []string{"mode", "base", "limit", "interval"} {
+			if ce.IsSet(name) {
+				return "", usageError{fmt.Sprintf("Option --off cannot be combined with --%s", name)}
+			}
+		}
+		return "", nil
+	}
+	switch mode {
+	case "exponential":
+		if ce.IsSet("interval") {
+			return "", usageError{"Option --interval is only valid with --mode periodic"}
+		}
+		base, limit := ce.Uint("base"), ce.Uint("limit")
+		if base < 2 {
+			return "", usageError{fmt.Sprintf("Invalid base %d, must be at least 2", base)}
+		}
+		if limit < base {
+			return "", usageError{fmt.Sprintf("Invalid limit %d, must not be less than the base %d", limit, base)}
+		}
+		return fmt.Sprintf("exponential:%d:%d", base, limit), nil
+	case "periodic":
+		if ce.IsSet("base") || ce.IsSet("limit") {
+			return "", usageError{"Options --base and --limit are only valid with --mode exponential"}
+		}
+		interval := ce.Uint("interval")
+		if interval < 1 {
+			return "", usageError{"Invalid interval 0, must be at least 1 second"}
+		}
+		return fmt.Sprintf("periodic:%d", interval), nil
+	case "":
+		return "", usageError{"Either --mode or --off is required"}
+	}
+	return "", usageError{fmt.Sprintf("Invalid autoscan mode ´%s´, use ´exponential´ or ´periodic´", mode)}
+}
+
+func (ce *cliExtended) scan_auto() error {
+	if ce.Args().Len() > 1 {
+		return usageError{fmt.Sprintf("Unexpected argument ´%s´, options go before the interface name", ce.Args().Get(1))}
+	}
+	arg, err := ce.autoscan_arg()
+	if err != nil {
+		return err
+	}
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	if err := iface.AutoScan(ce.ctx(), arg); err != nil {
+		return fmt.Errorf("%s: %w", ifname, err)
+	}
+	if len(arg) == 0 {
+		fmt.Println("Disabled autoscan on interface", ifname)
+	} else {
+		fmt.Printf("Set autoscan of interface %s to %s\n", ifname, arg)
+	}
+	return nil
+}
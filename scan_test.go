@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestAutoscanArg(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		invalid bool
+	}{
+		{args: []string{"--mode", "exponential"}, want: "exponential:3:300"},
+		{args: []string{"--mode", "exponential", "--base", "2", "--limit", "60"}, want: "exponential:2:60"},
+		{args: []string{"--mode", "exponential", "--base", "5", "--limit", "5"}, want: "exponential:5:5"},
+		{args: []string{"--mode", "periodic"}, want: "periodic:30"},
+		{args: []string{"--mode", "periodic", "--interval", "1"}, want: "periodic:1"},
+		{args: []string{"--off"}, want: ""},
+		{args: []string{"--mode", "exponential", "--base", "1"}, invalid: true},
+		{args: []string{"--mode", "exponential", "--base", "10", "--limit", "9"}, invalid: true},
+		{args: []string{"--mode", "exponential", "--interval", "10"}, invalid: true},
+		{args: []string{"--mode", "periodic", "--interval", "0"}, invalid: true},
+		{args: []string{"--mode", "periodic", "--base", "2"}, invalid: true},
+		{args: []string{"--mode", "periodic", "--limit", "60"}, invalid: true},
+		{args: []string{"--mode", "random"}, invalid: true},
+		{args: []string{"--off", "--mode", "periodic"}, invalid: true},
+		{args: []string{"--off", "--interval", "10"}, invalid: true},
+		{args: nil, invalid: true},
+	}
+	for _, tt := range tests {
+		/* The flags of ´scan auto´ with their defaults */
+		set := flag.NewFlagSet("auto", flag.ContinueOnError)
+		set.String("mode", "", "")
+		set.Uint("base", 3, "")
+		set.Uint("limit", 300, "")
+		set.Uint("interval", 30, "")
+		set.Bool("off", false, "")
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		ce := cliExtended{Context: cli.NewContext(nil, set, nil)}
+		arg, err := ce.autoscan_arg()
+		if tt.invalid {
+			if _, ok := err.(usageError); !ok {
+				t.Errorf("autoscan_arg(%q) = %q, %v, want a usage error", tt.args, arg, err)
+			}
+			continue
+		}
+		if err != nil || arg != tt.want {
+			t.Errorf("autoscan_arg(%q) = %q, %v, want %q", tt.args, arg, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"jp.net/wpactl/supplicant"
+)
+
+// security_classes are the values of ´scan-results --security´
+var security_classes = []string{"open", "owe", "wep", "wpa", "wpa2", "wpa3"}
+
+// bss_security classifies the security of a BSS. A BSS in a transition
+// mode belongs to several classes, e.g. ´wpa2´ and ´wpa3´.
+func bss_security(info *supplicant.BSSInfo) []string {
+	var classes []string
+	add := func(class string) {
+		for _, c := range classes {
+			if c == class {
+				return
+			}
+		}
+		classes = append(classes, class)
+	}
+	for _, km := range info.RSN.KeyMgmt {
+		switch {
+		case km == "owe":
+			add("owe")
+		case km == "sae" || km == "ft-sae" || km == "sae-ext-key" || km == "ft-sae-ext-key" || strings.HasPrefix(km, "wpa-eap-suite-b"):
+			add("wpa3")
+		case strings.HasPrefix(km, "wpa-"):
+			add("wpa2")
+		}
+	}
+	if len(info.WPA.KeyMgmt) > 0 {
+		add("wpa")
+	}
+	if len(classes) == 0 {
+		if info.Privacy {
+			add("wep")
+		} else {
+			add("open")
+		}
+	}
+	return classes
+}
+
+// bssFilter selects, orders and limits scan results
+type bssFilter struct {
+	ssid       *regexp.Regexp
+	bands      []*band
+	min_signal *int
+	max_age    *uint
+	security   []string
+	sort       string
+	limit      int
+	unique     bool
+	// save is the file for the snapshot of the shown BSSs
+	save string
+}
+
+// new_bss_filter builds the filter from the options of ´scan-results´
+func (ce *cliExtended) new_bss_filter() (*bssFilter, error) {
+	f := &bssFilter{sort: ce.String("sort"), limit: ce.Int("limit"), unique: ce.Bool("unique"), save: ce.Path("save")}
+	switch f.sort {
+	case "", "signal", "ssid", "freq", "age":
+	default:
+		return nil, usageError{fmt.Sprintf("Invalid sort key ´%s´, use ´signal´, ´ssid´, ´freq´ or ´age´", f.sort)}
+	}
+	if ce.IsSet("ssid") {
+		re, err := regexp.Compile(ce.String("ssid"))
+		if err != nil {
+			return nil, usageError{fmt.Sprintf("Invalid SSID pattern: %v", err)}
+		}
+		f.ssid = re
+	}
+	for _, name := range ce.StringSlice("band") {
+		b, err := parse_band(name)
+		if err != nil {
+			return nil, err
+		}
+		f.bands = append(f.bands, b)
+	}
+	if ce.IsSet("min-signal") {
+		min_signal := ce.Int("min-signal")
+		f.min_signal = &min_signal
+	}
+	if ce.IsSet("max-age") {
+		max_age := ce.Uint("max-age")
+		f.max_age = &max_age
+	}
+	for _, class := range ce.StringSlice("security") {
+		valid := false
+		for _, c := range security_classes {
+			valid = valid || c == class
+		}
+		if !valid {
+			return nil, usageError{fmt.Sprintf("Invalid security ´%s´, use one of %s", class, strings.Join(security_classes, ", "))}
+		}
+		f.security = append(f.security, class)
+	}
+	return f, nil
+}
+
+func (f *bssFilter) match(info *supplicant.BSSInfo) bool {
+	if f.ssid != nil {
+		ssid, ok := printable_ssid(info.SSID)
+		if !ok {
+			ssid = fmt.Sprintf("%x", info.SSID)
+		}
+		if !f.ssid.MatchString(ssid) {
+			return false
+		}
+	}
+	if len(f.bands) > 0 {
+		b := band_of(uint32(info.Frequency))
+		found := false
+		for _, want := range f.bands {
+			found = found || want == b
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.min_signal != nil && int(info.Signal) < *f.min_signal {
+		return false
+	}
+	if f.max_age != nil && uint(info.Age) > *f.max_age {
+		return false
+	}
+	if len(f.security) > 0 {
+		found := false
+		for _, class := range bss_security(info) {
+			for _, want := range f.security {
+				found = found || class == want
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the matching BSSs in the requested order. With unique
+// only the strongest BSS of each SSID is kept.
+func (f *bssFilter) apply(infos []*supplicant.BSSInfo) []*supplicant.BSSInfo {
+	result := make([]*supplicant.BSSInfo, 0, len(infos))
+	for _, info := range infos {
+		if f.match(info) {
+			result = append(result, info)
+		}
+	}
+	if f.unique {
+		strongest := make(map[string]*supplicant.BSSInfo)
+		for _, info := range result {
+			if best, ok := strongest[string(info.SSID)]; !ok || info.Signal > best.Signal {
+				strongest[string(info.SSID)] = info
+			}
+		}
+		unique := result[:0]
+		for _, info := range result {
+			if strongest[string(info.SSID)] == info {
+				unique = append(unique, info)
+			}
+		}
+		result = unique
+	}
+	var less func(a, b *supplicant.BSSInfo) bool
+	switch f.sort {
+	case "signal":
+		less = func(a, b *supplicant.BSSInfo) bool { return a.Signal > b.Signal }
+	case "ssid":
+		less = func(a, b *supplicant.BSSInfo) bool { return string(a.SSID) < string(b.SSID) }
+	case "freq":
+		less = func(a, b *supplicant.BSSInfo) bool { return a.Frequency < b.Frequency }
+	case "age":
+		less = func(a, b *supplicant.BSSInfo) bool { return a.Age < b.Age }
+	}
+	if less != nil {
+		sort.SliceStable(result, func(i, j int) bool { return less(result[i], result[j]) })
+	}
+	if f.limit > 0 && len(result) > f.limit {
+		result = result[:f.limit]
+	}
+	return result
+}
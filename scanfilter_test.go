@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"jp.net/wpactl/supplicant"
+)
+
+func TestBSSSecurity(t *testing.T) {
+	tests := []struct {
+		rsn     []string
+		wpa     []string
+		privacy bool
+		want    []string
+	}{
+		{want: []string{"open"}},
+		{privacy: true, want: []string{"wep"}},
+		{rsn: []string{"owe"}, privacy: true, want: []string{"owe"}},
+		{rsn: []string{"wpa-psk"}, privacy: true, want: []string{"wpa2"}},
+		{rsn: []string{"wpa-ft-eap", "wpa-eap-sha256"}, privacy: true, want: []string{"wpa2"}},
+		{rsn: []string{"sae"}, privacy: true, want: []string{"wpa3"}},
+		{rsn: []string{"ft-sae"}, privacy: true, want: []string{"wpa3"}},
+		{rsn: []string{"sae-ext-key"}, privacy: true, want: []string{"wpa3"}},
+		{rsn: []string{"ft-sae-ext-key"}, privacy: true, want: []string{"wpa3"}},
+		{rsn: []string{"wpa-eap-suite-b-192"}, privacy: true, want: []string{"wpa3"}},
+		{rsn: []string{"wpa-psk", "sae"}, privacy: true, want: []string{"wpa2", "wpa3"}},
+		{rsn: []string{"sae", "sae-ext-key"}, privacy: true, want: []string{"wpa3"}},
+		{rsn: []string{"wpa-psk"}, wpa: []string{"wpa-psk"}, privacy: true, want: []string{"wpa2", "wpa"}},
+		{wpa: []string{"wpa-psk"}, privacy: true, want: []string{"wpa"}},
+	}
+	for _, tt := range tests {
+		info := &supplicant.BSSInfo{Privacy: tt.privacy, RSN: supplicant.Security{KeyMgmt: tt.rsn}, WPA: supplicant.Security{KeyMgmt: tt.wpa}}
+		if got := bss_security(info); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bss_security(rsn %v, wpa %v, privacy %v) = %v, want %v", tt.rsn, tt.wpa, tt.privacy, got, tt.want)
+		}
+	}
+}
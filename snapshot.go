@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// snapshotDocument is a saved ´scan-results´ document
+type snapshotDocument struct {
+	Kind    string       `json:"kind" yaml:"kind"`
+	Version int          `json:"version" yaml:"version"`
+	Data    []*bssOutput `json:"data" yaml:"data"`
+}
+
+// save_snapshot writes the scan results as JSON document to path
+func save_snapshot(path string, results []*bssOutput) error {
+	doc := outputDocument{Kind: "scan-results", Version: outputSchemaVersion, Data: results}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// read_snapshot reads a document saved by ´scan-results --save´ or
+// printed by ´scan-results --output json|yaml´
+func read_snapshot(path string) ([]*bssOutput, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc snapshotDocument
+	/* YAML is a superset of JSON, so this reads both */
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, usageError{fmt.Sprintf("%s: invalid snapshot: %v", path, err)}
+	}
+	if doc.Kind != "scan-results" || doc.Version < 1 || doc.Version > outputSchemaVersion {
+		return nil, usageError{fmt.Sprintf("%s: not a scan-results document of version %d", path, outputSchemaVersion)}
+	}
+	return doc.Data, nil
+}
+
+type bssChangeOutput struct {
+	Field string      `json:"field" yaml:"field"`
+	Old   interface{} `json:"old" yaml:"old"`
+	New   interface{} `json:"new" yaml:"new"`
+}
+
+type bssDiffOutput struct {
+	BSSID   string            `json:"bssid" yaml:"bssid"`
+	SSID    *string           `json:"ssid" yaml:"ssid"`
+	Changes []bssChangeOutput `json:"changes" yaml:"changes"`
+}
+
+type scanDiffOutput struct {
+	Appeared    []*bssOutput    `json:"appeared" yaml:"appeared"`
+	Disappeared []*bssOutput    `json:"disappeared" yaml:"disappeared"`
+	Changed     []bssDiffOutput `json:"changed" yaml:"changed"`
+}
+
+func bss_ssid_text(b *bssOutput) string {
+	if b.SSID != nil {
+		return *b.SSID
+	}
+	return b.SSIDHex
+}
+
+// diff_bss compares two snapshots of the same BSS. Signal changes are
+// reported if they exceed threshold dB.
+func diff_bss(a, b *bssOutput, threshold int) []bssChangeOutput {
+	var changes []bssChangeOutput
+	if a.SSIDHex != b.SSIDHex {
+		changes = append(changes, bssChangeOutput{"ssid", bss_ssid_text(a), bss_ssid_text(b)})
+	}
+	if a.Frequency != b.Frequency {
+		changes = append(changes, bssChangeOutput{"frequency", a.Frequency, b.Frequency})
+	}
+	if strings.Join(a.Security, " ") != strings.Join(b.Security, " ") {
+		changes = append(changes, bssChangeOutput{"security", a.Security, b.Security})
+	}
+	if delta := int(b.Signal) - int(a.Signal); delta > threshold || -delta > threshold {
+		changes = append(changes, bssChangeOutput{"signal", a.Signal, b.Signal})
+	}
+	return changes
+}
+
+func diff_snapshots(before, after []*bssOutput, threshold int) *scanDiffOutput {
+	diff := &scanDiffOutput{Appeared: []*bssOutput{}, Disappeared: []*bssOutput{}, Changed: []bssDiffOutput{}}
+	old := make(map[string]*bssOutput, len(before))
+	for _, b := range before {
+		old[b.BSSID] = b
+	}
+	seen := make(map[string]bool, len(after))
+	for _, b := range after {
+		seen[b.BSSID] = true
+		a, ok := old[b.BSSID]
+		if !ok {
+			diff.Appeared = append(diff.Appeared, b)
+			continue
+		}
+		if changes := diff_bss(a, b, threshold); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, bssDiffOutput{BSSID: b.BSSID, SSID: b.SSID, Changes: changes})
+		}
+	}
+	for _, a := range before {
+		if !seen[a.BSSID] {
+			diff.Disappeared = append(diff.Disappeared, a)
+		}
+	}
+	sort.Slice(diff.Appeared, func(i, j int) bool { return diff.Appeared[i].BSSID < diff.Appeared[j].BSSID })
+	sort.Slice(diff.Disappeared, func(i, j int) bool { return diff.Disappeared[i].BSSID < diff.Disappeared[j].BSSID })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].BSSID < diff.Changed[j].BSSID })
+	return diff
+}
+
+func change_text(c bssChangeOutput) string {
+	switch c.Field {
+	case "frequency":
+		from, to := c.Old.(uint16), c.New.(uint16)
+		return fmt.Sprintf("channel %d -> %d (%d -> %d MHz)", channel_number(uint32(from)), channel_number(uint32(to)), from, to)
+	case "security":
+		return fmt.Sprintf("security %v -> %v", c.Old, c.New)
+	case "signal":
+		from, to := c.Old.(int16), c.New.(int16)
+		return fmt.Sprintf("signal %d -> %d dBm (%+d)", from, to, to-from)
+	}
+	return fmt.Sprintf("%s %v -> %v", c.Field, c.Old, c.New)
+}
+
+func (ce *cliExtended) scan_diff() error {
+	if ce.Args().Len() != 2 {
+		return usageError{"Two snapshot files required"}
+	}
+	before, err := read_snapshot(ce.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	after, err := read_snapshot(ce.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	diff := diff_snapshots(before, after, ce.Int("threshold"))
+	return ce.print_output("scan-diff", diff, func() {
+		for _, b := range diff.Appeared {
+			fmt.Printf("+ %s %-32s %4d MHz %4d dBm %v\n", b.BSSID, bss_ssid_text(b), b.Frequency, b.Signal, b.Security)
+		}
+		for _, b := range diff.Disappeared {
+			fmt.Printf("- %s %-32s %4d MHz %4d dBm %v\n", b.BSSID, bss_ssid_text(b), b.Frequency, b.Signal, b.Security)
+		}
+		for _, d := range diff.Changed {
+			name := ""
+			if d.SSID != nil {
+				name = *d.SSID
+			}
+			for _, c := range d.Changes {
+				fmt.Printf("~ %s %-32s %s\n", d.BSSID, name, change_text(c))
+			}
+		}
+	})
+}
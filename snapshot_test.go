@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func snapshot_bss(bssid, ssid string, freq uint16, signal int16, security ...string) *bssOutput {
+	return &bssOutput{BSSID: bssid, SSID: &ssid, SSIDHex: ssid, Frequency: freq, Signal: signal, Security: security}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	office := snapshot_bss("00:11:22:33:44:55", "Office", 2412, -50, "wpa2")
+	guest := snapshot_bss("00:11:22:33:44:66", "Guest", 5180, -60, "open")
+	lab := snapshot_bss("00:11:22:33:44:77", "Lab", 5500, -70, "wpa3")
+	tests := []struct {
+		name        string
+		before      []*bssOutput
+		after       []*bssOutput
+		threshold   int
+		appeared    []string
+		disappeared []string
+		changed     map[string][]string
+	}{
+		{name: "empty"},
+		{name: "unchanged", before: []*bssOutput{office, guest}, after: []*bssOutput{guest, office}, threshold: 10},
+		{name: "added", before: []*bssOutput{office}, after: []*bssOutput{lab, office, guest}, threshold: 10, appeared: []string{guest.BSSID, lab.BSSID}},
+		{name: "removed", before: []*bssOutput{lab, office, guest}, after: []*bssOutput{office}, threshold: 10, disappeared: []string{guest.BSSID, lab.BSSID}},
+		{name: "replaced", before: []*bssOutput{office}, after: []*bssOutput{guest}, threshold: 10, appeared: []string{guest.BSSID}, disappeared: []string{office.BSSID}},
+		{
+			name:      "channel and security",
+			before:    []*bssOutput{office, guest},
+			after:     []*bssOutput{snapshot_bss(office.BSSID, "Office", 2437, -50, "wpa2", "wpa3"), guest},
+			threshold: 10,
+			changed:   map[string][]string{office.BSSID: {"frequency", "security"}},
+		},
+		{
+			name:      "renamed",
+			before:    []*bssOutput{office},
+			after:     []*bssOutput{snapshot_bss(office.BSSID, "Office-2", 2412, -50, "wpa2")},
+			threshold: 10,
+			changed:   map[string][]string{office.BSSID: {"ssid"}},
+		},
+		{
+			name:      "signal within threshold",
+			before:    []*bssOutput{office},
+			after:     []*bssOutput{snapshot_bss(office.BSSID, "Office", 2412, -60, "wpa2")},
+			threshold: 10,
+		},
+		{
+			name:      "signal beyond threshold",
+			before:    []*bssOutput{office, guest},
+			after:     []*bssOutput{snapshot_bss(office.BSSID, "Office", 2412, -61, "wpa2"), snapshot_bss(guest.BSSID, "Guest", 5180, -49, "open")},
+			threshold: 10,
+			changed:   map[string][]string{office.BSSID: {"signal"}, guest.BSSID: {"signal"}},
+		},
+		{
+			name:      "zero threshold",
+			before:    []*bssOutput{office},
+			after:     []*bssOutput{snapshot_bss(office.BSSID, "Office", 2412, -51, "wpa2")},
+			threshold: 0,
+			changed:   map[string][]string{office.BSSID: {"signal"}},
+		},
+	}
+	bssids := func(bsss []*bssOutput) []string {
+		var result []string
+		for _, b := range bsss {
+			result = append(result, b.BSSID)
+		}
+		return result
+	}
+	for _, tt := range tests {
+		diff := diff_snapshots(tt.before, tt.after, tt.threshold)
+		if got := bssids(diff.Appeared); !reflect.DeepEqual(got, tt.appeared) {
+			t.Errorf("%s: appeared %v, want %v", tt.name, got, tt.appeared)
+		}
+		if got := bssids(diff.Disappeared); !reflect.DeepEqual(got, tt.disappeared) {
+			t.Errorf("%s: disappeared %v, want %v", tt.name, got, tt.disappeared)
+		}
+		changed := make(map[string][]string)
+		for _, c := range diff.Changed {
+			for _, change := range c.Changes {
+				changed[c.BSSID] = append(changed[c.BSSID], change.Field)
+			}
+		}
+		if len(changed) > 0 || len(tt.changed) > 0 {
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("%s: changed %v, want %v", tt.name, changed, tt.changed)
+			}
+		}
+	}
+}
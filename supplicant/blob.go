@@ -0,0 +1,45 @@
+package supplicant
+
+import (
+	"context"
+	"sort"
+)
+
+// Blob is a named chunk of data, e.g. a certificate, which networks can
+// reference as "blob://<name>"
+type Blob struct {
+	Name string
+	Data []byte
+}
+
+// Blobs returns the blobs of the interface sorted by name
+func (i *Interface) Blobs(ctx context.Context) ([]Blob, error) {
+	v, err := i.get(ctx, "Blobs")
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.Value().(map[string][]byte)
+	blobs := make([]Blob, 0, len(m))
+	for name, data := range m {
+		blobs = append(blobs, Blob{Name: name, Data: data})
+	}
+	sort.Slice(blobs, func(a, b int) bool { return blobs[a].Name < blobs[b].Name })
+	return blobs, nil
+}
+
+// AddBlob uploads a blob to the interface
+func (i *Interface) AddBlob(ctx context.Context, b Blob) error {
+	return i.call(ctx, "AddBlob", b.Name, b.Data).Err
+}
+
+// GetBlob downloads the named blob
+func (i *Interface) GetBlob(ctx context.Context, name string) (b Blob, err error) {
+	b.Name = name
+	err = i.call(ctx, "GetBlob", name).Store(&b.Data)
+	return
+}
+
+// RemoveBlob removes the named blob
+func (i *Interface) RemoveBlob(ctx context.Context, name string) error {
+	return i.call(ctx, "RemoveBlob", name).Err
+}
@@ -0,0 +1,141 @@
+package supplicant
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// BSS represents a basic service set found by a scan
+type BSS struct {
+	object
+}
+
+// BSS returns the BSS object for the given path without checking its
+// existence
+func (s *Supplicant) BSS(path dbus.ObjectPath) *BSS {
+	return &BSS{object{s: s, path: path, iface: BSSIface}}
+}
+
+// Path returns the D-Bus object path of the BSS
+func (b *BSS) Path() dbus.ObjectPath {
+	return b.path
+}
+
+// Property returns the raw value of the named BSS property
+func (b *BSS) Property(ctx context.Context, name string) (interface{}, error) {
+	v, err := b.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return v.Value(), nil
+}
+
+// Security describes the WPA or RSN information element of a BSS
+type Security struct {
+	KeyMgmt   []string
+	Pairwise  []string
+	Group     string
+	MgmtGroup string
+}
+
+func newSecurity(v interface{}) (sec Security) {
+	m, _ := v.(map[string]dbus.Variant)
+	sec.KeyMgmt, _ = m["KeyMgmt"].Value().([]string)
+	sec.Pairwise, _ = m["Pairwise"].Value().([]string)
+	sec.Group, _ = m["Group"].Value().(string)
+	sec.MgmtGroup, _ = m["MgmtGroup"].Value().(string)
+	return
+}
+
+// BSSInfo holds the properties of a BSS
+type BSSInfo struct {
+	Path      dbus.ObjectPath
+	SSID      []byte
+	BSSID     net.HardwareAddr
+	Mode      string
+	Frequency uint16
+	Signal    int16
+	Age       uint32
+	Privacy   bool
+	WPA       Security
+	RSN       Security
+	Rates     []uint32
+	IEs       []byte
+}
+
+func newBSSInfo(path dbus.ObjectPath, props map[string]dbus.Variant) *BSSInfo {
+	info := &BSSInfo{Path: path}
+	info.Update(props)
+	return info
+}
+
+// Update applies changed properties, e.g. from a PropertiesChanged
+// signal. Properties missing in props keep their value.
+func (info *BSSInfo) Update(props map[string]dbus.Variant) {
+	for name, v := range props {
+		switch name {
+		case "SSID":
+			info.SSID, _ = v.Value().([]byte)
+		case "BSSID":
+			bssid, _ := v.Value().([]byte)
+			info.BSSID = net.HardwareAddr(bssid)
+		case "Mode":
+			info.Mode, _ = v.Value().(string)
+		case "Frequency":
+			info.Frequency, _ = v.Value().(uint16)
+		case "Signal":
+			info.Signal, _ = v.Value().(int16)
+		case "Age":
+			info.Age, _ = v.Value().(uint32)
+		case "Privacy":
+			info.Privacy, _ = v.Value().(bool)
+		case "WPA":
+			info.WPA = newSecurity(v.Value())
+		case "RSN":
+			info.RSN = newSecurity(v.Value())
+		case "Rates":
+			info.Rates, _ = v.Value().([]uint32)
+		case "IEs":
+			info.IEs, _ = v.Value().([]byte)
+		}
+	}
+}
+
+// Info fetches all properties of the BSS with a single call
+func (b *BSS) Info(ctx context.Context) (*BSSInfo, error) {
+	props, err := b.getAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newBSSInfo(b.path, props), nil
+}
+
+// BSSInfos fetches the properties of all BSSs of the interface. BSSs which
+// vanish while they are fetched are left out.
+func (i *Interface) BSSInfos(ctx context.Context) ([]*BSSInfo, error) {
+	bsss, err := i.BSSs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*BSSInfo, len(bsss))
+	err = i.s.parallel(ctx, len(bsss), func(ctx context.Context, idx int) (err error) {
+		infos[idx], err = bsss[idx].Info(ctx)
+		if errors.Is(err, ErrUnknownObject) {
+			err = nil
+		}
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := infos[:0]
+	for _, info := range infos {
+		if info != nil {
+			result = append(result, info)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package supplicant
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Capabilities lists what the driver of an interface supports as reported
+// by the Capabilities property of the interface
+type Capabilities struct {
+	Pairwise    []string
+	Group       []string
+	GroupMgmt   []string
+	KeyMgmt     []string
+	Protocol    []string
+	AuthAlg     []string
+	Scan        []string
+	Modes       []string
+	MaxScanSSID int32
+}
+
+// Capabilities returns the capabilities of the interface
+func (i *Interface) Capabilities(ctx context.Context) (*Capabilities, error) {
+	v, err := i.get(ctx, "Capabilities")
+	if err != nil {
+		return nil, err
+	}
+	props, _ := v.Value().(map[string]dbus.Variant)
+	list := func(name string) []string {
+		l, _ := props[name].Value().([]string)
+		if l == nil {
+			l = []string{}
+		}
+		return l
+	}
+	c := &Capabilities{
+		Pairwise:  list("Pairwise"),
+		Group:     list("Group"),
+		GroupMgmt: list("GroupMgmt"),
+		KeyMgmt:   list("KeyMgmt"),
+		Protocol:  list("Protocol"),
+		AuthAlg:   list("AuthAlg"),
+		Scan:      list("Scan"),
+		Modes:     list("Modes"),
+	}
+	c.MaxScanSSID, _ = props["MaxScanSSID"].Value().(int32)
+	return c, nil
+}
+
+func (s *Supplicant) stringsProperty(ctx context.Context, name string) ([]string, error) {
+	v, err := s.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	l, _ := v.Value().([]string)
+	if l == nil {
+		l = []string{}
+	}
+	return l, nil
+}
+
+// Capabilities returns the features the supplicant was built with, e.g.
+// "ap" or "mesh"
+func (s *Supplicant) Capabilities(ctx context.Context) ([]string, error) {
+	return s.stringsProperty(ctx, "Capabilities")
+}
+
+// EapMethods returns the EAP methods the supplicant supports
+func (s *Supplicant) EapMethods(ctx context.Context) ([]string, error) {
+	return s.stringsProperty(ctx, "EapMethods")
+}
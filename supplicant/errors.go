@@ -0,0 +1,107 @@
+package supplicant
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Error is an error reply of wpa_supplicant or of the message bus. Use
+// errors.Is with the Err* values to check for a specific error.
+type Error struct {
+	// Name is the D-Bus error name, e.g. fi.w1.wpa_supplicant1.InterfaceUnknown
+	Name string
+	// Message is the explanation sent along with the error, if any
+	Message string
+}
+
+// Errors replied by wpa_supplicant and the message bus
+var (
+	ErrUnknownError      = &Error{Name: RootIface + ".UnknownError"}
+	ErrInvalidArgs       = &Error{Name: RootIface + ".InvalidArgs"}
+	ErrInterfaceExists   = &Error{Name: RootIface + ".InterfaceExists"}
+	ErrInterfaceUnknown  = &Error{Name: RootIface + ".InterfaceUnknown"}
+	ErrInterfaceDisabled = &Error{Name: RootIface + ".InterfaceDisabled"}
+	ErrNetworkUnknown    = &Error{Name: RootIface + ".NetworkUnknown"}
+	ErrBlobExists        = &Error{Name: RootIface + ".BlobExists"}
+	ErrBlobUnknown       = &Error{Name: RootIface + ".BlobUnknown"}
+	ErrNotConnected      = &Error{Name: RootIface + ".NotConnected"}
+	ErrScanError         = &Error{Name: InterfaceIface + ".ScanError"}
+	ErrAccessDenied      = &Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}
+	ErrServiceUnknown    = &Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}
+	ErrUnknownObject     = &Error{Name: "org.freedesktop.DBus.Error.UnknownObject"}
+)
+
+var errorTexts = map[string]string{
+	ErrUnknownError.Name:      "wpa_supplicant reported an error",
+	ErrInvalidArgs.Name:       "invalid argument",
+	ErrInterfaceExists.Name:   "interface is already managed by wpa_supplicant",
+	ErrInterfaceUnknown.Name:  "interface is not managed by wpa_supplicant",
+	ErrInterfaceDisabled.Name: "interface is disabled",
+	ErrNetworkUnknown.Name:    "no such network",
+	ErrBlobExists.Name:        "blob already exists",
+	ErrBlobUnknown.Name:       "no such blob",
+	ErrNotConnected.Name:      "interface is not connected",
+	ErrScanError.Name:         "scan request rejected",
+	ErrAccessDenied.Name:      "permission denied",
+	ErrServiceUnknown.Name:    "wpa_supplicant is not running",
+	ErrUnknownObject.Name:     "no such object",
+}
+
+func (e *Error) Error() string {
+	text, ok := errorTexts[e.Name]
+	if !ok {
+		text = e.Name
+	}
+	if len(e.Message) > 0 {
+		return text + " (" + e.Message + ")"
+	}
+	return text
+}
+
+// Is reports whether target is an Error with the same name
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Name == e.Name
+}
+
+// TimeoutError is returned if a D-Bus call or a wait for a signal ran out
+// of time. It wraps context.DeadlineExceeded.
+type TimeoutError struct {
+	// Op is what was waited for, e.g. "waiting for the reply to Interface.Scan"
+	Op string
+	// After is the time limit which was exceeded
+	After time.Duration
+	Err   error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v %s", e.After, e.Op)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError converts D-Bus error replies into *Error
+func wrapError(err error) error {
+	var derr dbus.Error
+	if errors.As(err, &derr) {
+		return newError(derr)
+	}
+	var pderr *dbus.Error
+	if errors.As(err, &pderr) && pderr != nil {
+		return newError(*pderr)
+	}
+	return err
+}
+
+func newError(derr dbus.Error) *Error {
+	e := &Error{Name: derr.Name}
+	if len(derr.Body) > 0 {
+		e.Message, _ = derr.Body[0].(string)
+	}
+	return e
+}
@@ -0,0 +1,138 @@
+package fake
+
+import (
+	"net"
+	"reflect"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// BSSConfig describes a fake scan result
+type BSSConfig struct {
+	SSID      string `json:"ssid"`
+	BSSID     string `json:"bssid"`
+	Frequency uint16 `json:"frequency"`
+	Signal    int16  `json:"signal"`
+	Age       uint32 `json:"age,omitempty"`
+	// Mode defaults to "infrastructure"
+	Mode string `json:"mode,omitempty"`
+	// KeyMgmt lists the key management suites of the RSN element, e.g.
+	// "wpa-psk" or "sae". An empty list makes an open network.
+	KeyMgmt  []string `json:"key_mgmt,omitempty"`
+	Pairwise []string `json:"pairwise,omitempty"`
+	Group    string   `json:"group,omitempty"`
+	// WPA announces the suites in a legacy WPA element instead of RSN
+	WPA   bool     `json:"wpa,omitempty"`
+	IEs   []byte   `json:"ies,omitempty"`
+	Rates []uint32 `json:"rates,omitempty"`
+}
+
+func (cfg BSSConfig) security() (wpa, rsn map[string]dbus.Variant) {
+	wpa = map[string]dbus.Variant{}
+	rsn = map[string]dbus.Variant{}
+	if len(cfg.KeyMgmt) == 0 {
+		return
+	}
+	pairwise, group := cfg.Pairwise, cfg.Group
+	if len(pairwise) == 0 {
+		pairwise = []string{"ccmp"}
+	}
+	if len(group) == 0 {
+		group = "ccmp"
+	}
+	sec := map[string]dbus.Variant{
+		"KeyMgmt":  dbus.MakeVariant(cfg.KeyMgmt),
+		"Pairwise": dbus.MakeVariant(pairwise),
+		"Group":    dbus.MakeVariant(group),
+	}
+	if cfg.WPA {
+		return sec, rsn
+	}
+	sec["MgmtGroup"] = dbus.MakeVariant("aes128cmac")
+	return wpa, sec
+}
+
+// BSS is a fake scan result
+type BSS struct {
+	object
+	cfg BSSConfig
+}
+
+func newBSS(s *Supplicant, path dbus.ObjectPath, cfg BSSConfig) *BSS {
+	b := &BSS{object: newObject(s, path, BSSIface)}
+	for _, name := range []string{"BSSID", "SSID", "WPA", "RSN", "WPS", "IEs", "Privacy", "Mode", "Frequency", "Rates", "Signal", "Age"} {
+		b.prop(name, nil, false)
+	}
+	b.assign(cfg)
+	return b
+}
+
+// assign copies the configuration into the properties without emitting
+// change signals and returns the names of the changed properties
+func (b *BSS) assign(cfg BSSConfig) (changed []string) {
+	bssid, _ := net.ParseMAC(cfg.BSSID)
+	if bssid == nil {
+		bssid = net.HardwareAddr{}
+	}
+	mode := cfg.Mode
+	if len(mode) == 0 {
+		mode = "infrastructure"
+	}
+	ies, rates := cfg.IEs, cfg.Rates
+	if ies == nil {
+		ies = []byte{}
+	}
+	if rates == nil {
+		rates = []uint32{54000000, 48000000, 36000000, 24000000, 18000000, 12000000, 9000000, 6000000}
+	}
+	wpa, rsn := cfg.security()
+	values := map[string]interface{}{
+		"BSSID":     []byte(bssid),
+		"SSID":      []byte(cfg.SSID),
+		"WPA":       wpa,
+		"RSN":       rsn,
+		"WPS":       map[string]dbus.Variant{"Type": dbus.MakeVariant("")},
+		"IEs":       ies,
+		"Privacy":   len(cfg.KeyMgmt) > 0,
+		"Mode":      mode,
+		"Frequency": cfg.Frequency,
+		"Rates":     rates,
+		"Signal":    cfg.Signal,
+		"Age":       cfg.Age,
+	}
+	b.cfg = cfg
+	for name, v := range values {
+		if p := b.props[name]; !reflect.DeepEqual(p.value, v) {
+			p.value = v
+			changed = append(changed, name)
+		}
+	}
+	return
+}
+
+// update replaces the configuration and emits the changes
+func (b *BSS) update(cfg BSSConfig) {
+	if changed := b.assign(cfg); len(changed) > 0 {
+		b.emitChanged(changed...)
+	}
+}
+
+// Path returns the D-Bus object path of the BSS
+func (b *BSS) Path() dbus.ObjectPath {
+	return b.path
+}
+
+// Update replaces the configuration of the BSS, e.g. to change the signal
+// strength, and emits PropertiesChanged for the changed properties
+func (b *BSS) Update(cfg BSSConfig) {
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	b.update(cfg)
+}
+
+// Config returns the configuration of the BSS
+func (b *BSS) Config() BSSConfig {
+	b.s.mu.Lock()
+	defer b.s.mu.Unlock()
+	return b.cfg
+}
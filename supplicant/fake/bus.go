@@ -0,0 +1,84 @@
+package fake
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// Bus is a private dbus-daemon instance
+type Bus struct {
+	// Address is the D-Bus address of the bus
+	Address string
+	dir     string
+	cmd     *exec.Cmd
+}
+
+// StartBus launches a private dbus-daemon. The dbus-daemon executable must
+// be in $PATH.
+func StartBus() (*Bus, error) {
+	dir, err := ioutil.TempDir("", "wpactl-fake-bus")
+	if err != nil {
+		return nil, err
+	}
+	b := &Bus{dir: dir}
+	config := filepath.Join(dir, "bus.conf")
+	socket := filepath.Join(dir, "bus.sock")
+	if err := ioutil.WriteFile(config, []byte(fmt.Sprintf(busConfig, socket)), 0644); err != nil {
+		b.Close()
+		return nil, err
+	}
+	b.cmd = exec.Command("dbus-daemon", "--config-file="+config, "--nofork", "--print-address")
+	b.cmd.Stderr = os.Stderr
+	stdout, err := b.cmd.StdoutPipe()
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	if err := b.cmd.Start(); err != nil {
+		b.Close()
+		return nil, err
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		b.Close()
+		return nil, errors.New("dbus-daemon did not report its address")
+	}
+	b.Address = strings.TrimSpace(line)
+	return b, nil
+}
+
+// Connect opens a new connection to the bus
+func (b *Bus) Connect() (*dbus.Conn, error) {
+	return dbus.Connect(b.Address)
+}
+
+// Close terminates the bus daemon and removes its socket
+func (b *Bus) Close() error {
+	if b.cmd != nil && b.cmd.Process != nil {
+		b.cmd.Process.Kill()
+		b.cmd.Wait()
+	}
+	return os.RemoveAll(b.dir)
+}
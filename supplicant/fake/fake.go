@@ -0,0 +1,282 @@
+// Package fake implements an in-process wpa_supplicant D-Bus service. It
+// exports the fi.w1.wpa_supplicant1 objects on a connection, typically to
+// a private bus started with StartBus, and can be scripted to return
+// canned scan results or to fail calls.
+package fake
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	Service             = "fi.w1.wpa_supplicant1"
+	Path                = dbus.ObjectPath("/fi/w1/wpa_supplicant1")
+	RootIface           = Service
+	InterfaceIface      = RootIface + ".Interface"
+	BSSIface            = RootIface + ".BSS"
+	NetworkIface        = RootIface + ".Network"
+	propertiesIface     = "org.freedesktop.DBus.Properties"
+	introspectableIface = "org.freedesktop.DBus.Introspectable"
+	noObjectPath        = dbus.ObjectPath("/")
+)
+
+// D-Bus error names as used by wpa_supplicant
+const (
+	ErrUnknownError      = RootIface + ".UnknownError"
+	ErrInvalidArgs       = RootIface + ".InvalidArgs"
+	ErrInterfaceExists   = RootIface + ".InterfaceExists"
+	ErrInterfaceUnknown  = RootIface + ".InterfaceUnknown"
+	ErrInterfaceDisabled = RootIface + ".InterfaceDisabled"
+	ErrNotConnected      = RootIface + ".NotConnected"
+	ErrNetworkUnknown    = RootIface + ".NetworkUnknown"
+	ErrBlobExists        = RootIface + ".BlobExists"
+	ErrBlobUnknown       = RootIface + ".BlobUnknown"
+	ErrScanError         = InterfaceIface + ".ScanError"
+	ErrAccessDenied      = "org.freedesktop.DBus.Error.AccessDenied"
+)
+
+// NewError returns a D-Bus error with the given name and message
+func NewError(name, msg string) *dbus.Error {
+	return dbus.NewError(name, []interface{}{msg})
+}
+
+type property struct {
+	value    interface{}
+	writable bool
+	// set replaces the plain assignment of a value written over D-Bus
+	set func(v interface{}) *dbus.Error
+}
+
+// object holds what all exported fake objects have in common
+type object struct {
+	s       *Supplicant
+	path    dbus.ObjectPath
+	iface   string
+	props   map[string]*property
+	methods map[string]interface{}
+	fails   map[string]*dbus.Error
+}
+
+func newObject(s *Supplicant, path dbus.ObjectPath, iface string) object {
+	return object{
+		s:     s,
+		path:  path,
+		iface: iface,
+		props: make(map[string]*property),
+		fails: make(map[string]*dbus.Error),
+	}
+}
+
+func (o *object) prop(name string, value interface{}, writable bool) *property {
+	p := &property{value: value, writable: writable}
+	o.props[name] = p
+	return p
+}
+
+func (o *object) export() error {
+	conn := o.s.conn
+	if len(o.methods) > 0 {
+		if err := conn.ExportMethodTable(o.methods, o.path, o.iface); err != nil {
+			return err
+		}
+	}
+	propMethods := map[string]interface{}{
+		"Get":    o.dbusGet,
+		"GetAll": o.dbusGetAll,
+		"Set":    o.dbusSet,
+	}
+	if err := conn.ExportMethodTable(propMethods, o.path, propertiesIface); err != nil {
+		return err
+	}
+	return conn.Export(introspect.NewIntrospectable(o.node()), o.path, introspectableIface)
+}
+
+func (o *object) unexport() {
+	conn := o.s.conn
+	conn.Export(nil, o.path, o.iface)
+	conn.Export(nil, o.path, propertiesIface)
+	conn.Export(nil, o.path, introspectableIface)
+}
+
+// node builds the introspection data from the method table and properties
+func (o *object) node() *introspect.Node {
+	errType := reflect.TypeOf((*dbus.Error)(nil))
+	senderType := reflect.TypeOf(dbus.Sender(""))
+	iface := introspect.Interface{Name: o.iface}
+	for name, fn := range o.methods {
+		t := reflect.TypeOf(fn)
+		m := introspect.Method{Name: name}
+		for i := 0; i < t.NumIn(); i++ {
+			if t.In(i) != senderType {
+				m.Args = append(m.Args, introspect.Arg{Type: dbus.SignatureOfType(t.In(i)).String(), Direction: "in"})
+			}
+		}
+		for i := 0; i < t.NumOut(); i++ {
+			if t.Out(i) != errType {
+				m.Args = append(m.Args, introspect.Arg{Type: dbus.SignatureOfType(t.Out(i)).String(), Direction: "out"})
+			}
+		}
+		iface.Methods = append(iface.Methods, m)
+	}
+	sort.Slice(iface.Methods, func(a, b int) bool { return iface.Methods[a].Name < iface.Methods[b].Name })
+	for name, p := range o.props {
+		access := "read"
+		if p.writable {
+			access = "readwrite"
+		}
+		iface.Properties = append(iface.Properties, introspect.Property{
+			Name:   name,
+			Type:   dbus.SignatureOf(p.value).String(),
+			Access: access,
+		})
+	}
+	sort.Slice(iface.Properties, func(a, b int) bool { return iface.Properties[a].Name < iface.Properties[b].Name })
+	return &introspect.Node{
+		Name: string(o.path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: propertiesIface},
+			iface,
+		},
+	}
+}
+
+func (o *object) dbusGet(iface, name string) (dbus.Variant, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if err := o.fail("Get"); err != nil {
+		return dbus.Variant{}, err
+	}
+	p, err := o.lookup(iface, name)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	return dbus.MakeVariant(p.value), nil
+}
+
+func (o *object) dbusGetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if err := o.fail("GetAll"); err != nil {
+		return nil, err
+	}
+	if iface != o.iface {
+		return nil, NewError(ErrInvalidArgs, "No such interface")
+	}
+	all := make(map[string]dbus.Variant, len(o.props))
+	for name, p := range o.props {
+		all[name] = dbus.MakeVariant(p.value)
+	}
+	return all, nil
+}
+
+func (o *object) dbusSet(iface, name string, v dbus.Variant) *dbus.Error {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if err := o.fail("Set"); err != nil {
+		return err
+	}
+	p, err := o.lookup(iface, name)
+	if err != nil {
+		return err
+	}
+	if !p.writable {
+		return NewError(ErrInvalidArgs, fmt.Sprintf("Property %s is read-only", name))
+	}
+	if v.Signature() != dbus.SignatureOf(p.value) {
+		return NewError(ErrInvalidArgs, fmt.Sprintf("Invalid argument type: '%s'", v.Signature()))
+	}
+	if p.set != nil {
+		return p.set(v.Value())
+	}
+	o.setProperty(name, v.Value())
+	return nil
+}
+
+func (o *object) lookup(iface, name string) (*property, *dbus.Error) {
+	if iface != o.iface {
+		return nil, NewError(ErrInvalidArgs, "No such interface")
+	}
+	p, ok := o.props[name]
+	if !ok {
+		return nil, NewError(ErrInvalidArgs, fmt.Sprintf("No such property '%s'", name))
+	}
+	return p, nil
+}
+
+// fail returns the scripted error for the given method or nil
+func (o *object) fail(method string) *dbus.Error {
+	return o.fails[method]
+}
+
+// get returns the property value. The caller must hold s.mu.
+func (o *object) get(name string) interface{} {
+	return o.props[name].value
+}
+
+// setProperty changes a property and emits the change signals. The caller
+// must hold s.mu.
+func (o *object) setProperty(name string, value interface{}) {
+	o.props[name].value = value
+	o.emitChanged(name)
+}
+
+func (o *object) emitChanged(names ...string) {
+	changed := make(map[string]dbus.Variant, len(names))
+	for _, name := range names {
+		changed[name] = dbus.MakeVariant(o.props[name].value)
+	}
+	o.s.conn.Emit(o.path, propertiesIface+".PropertiesChanged", o.iface, changed, []string{})
+	o.s.conn.Emit(o.path, o.iface+".PropertiesChanged", changed)
+}
+
+func (o *object) emit(signal string, values ...interface{}) {
+	o.s.conn.Emit(o.path, o.iface+"."+signal, values...)
+}
+
+func (o *object) properties() map[string]dbus.Variant {
+	all := make(map[string]dbus.Variant, len(o.props))
+	for name, p := range o.props {
+		all[name] = dbus.MakeVariant(p.value)
+	}
+	return all
+}
+
+// Fail makes all following calls of the given D-Bus method, or of the
+// property methods Get, GetAll and Set, return err. A nil err removes the
+// scripted failure.
+func (o *object) Fail(method string, err *dbus.Error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if err == nil {
+		delete(o.fails, method)
+	} else {
+		o.fails[method] = err
+	}
+}
+
+// Property returns the current value of the named property
+func (o *object) Property(name string) interface{} {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if p, ok := o.props[name]; ok {
+		return p.value
+	}
+	return nil
+}
+
+// SetProperty changes the named property, emitting PropertiesChanged. It
+// adds the property if it does not exist.
+func (o *object) SetProperty(name string, value interface{}) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+	if _, ok := o.props[name]; !ok {
+		o.prop(name, value, false)
+	}
+	o.setProperty(name, value)
+}
@@ -0,0 +1,441 @@
+package fake
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// start serves the scenario on a private bus and returns the fake and a
+// client connection to the bus
+func start(t *testing.T, sc Scenario) (*Supplicant, *dbus.Conn) {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	bus, err := StartBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	conn, err := bus.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s, err := New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(sc); err != nil {
+		t.Fatal(err)
+	}
+	client, err := bus.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return s, client
+}
+
+// errorName returns the D-Bus error name of err, "" for nil
+func errorName(err error) string {
+	if err == nil {
+		return ""
+	}
+	if e, ok := err.(dbus.Error); ok {
+		return e.Name
+	}
+	return err.Error()
+}
+
+// signals subscribes to the signals of the given D-Bus interface
+func signals(t *testing.T, conn *dbus.Conn, path dbus.ObjectPath, iface string) chan *dbus.Signal {
+	t.Helper()
+	if err := conn.AddMatchSignal(dbus.WithMatchObjectPath(path), dbus.WithMatchInterface(iface)); err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan *dbus.Signal, 64)
+	conn.Signal(ch)
+	return ch
+}
+
+// waitSignal returns the next signal with the given member
+func waitSignal(t *testing.T, ch chan *dbus.Signal, member string) *dbus.Signal {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case sig := <-ch:
+			if strings.HasSuffix(sig.Name, "."+member) {
+				return sig
+			}
+		case <-timeout:
+			t.Fatalf("no %s signal", member)
+			return nil
+		}
+	}
+}
+
+func TestReadScenario(t *testing.T) {
+	sc, err := ReadScenario(strings.NewReader(`{"interfaces":[{"ifname":"wl0","scan_fails":true,"blobs":{"ca":"aGVsbG8="}}],"fail":{"GetInterface":"x.y"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Interfaces) != 1 || sc.Interfaces[0].Ifname != "wl0" || !sc.Interfaces[0].ScanFails || string(sc.Interfaces[0].Blobs["ca"]) != "hello" || sc.Fail["GetInterface"] != "x.y" {
+		t.Errorf("scenario %+v", sc)
+	}
+	if _, err := ReadScenario(strings.NewReader(`{"interfaces":[{"name":"wl0"}]}`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{Ifname: "wl0", State: "inactive"}}})
+	root := client.Object(Service, Path)
+	var path dbus.ObjectPath
+	if err := root.Call(RootIface+".GetInterface", 0, "wl0").Store(&path); err != nil || path != s.Interface("wl0").Path() {
+		t.Fatalf("GetInterface: %v %v", path, err)
+	}
+	state, err := client.Object(Service, path).GetProperty(InterfaceIface + ".State")
+	if err != nil || state.Value() != "inactive" {
+		t.Errorf("State %v %v", state, err)
+	}
+	tests := []struct {
+		method string
+		args   []interface{}
+		err    string
+	}{
+		{"GetInterface", []interface{}{"wl1"}, ErrInterfaceUnknown},
+		{"CreateInterface", []interface{}{map[string]dbus.Variant{"Ifname": dbus.MakeVariant("wl0")}}, ErrInterfaceExists},
+		{"CreateInterface", []interface{}{map[string]dbus.Variant{"Ifname": dbus.MakeVariant(uint32(1))}}, ErrInvalidArgs},
+		{"CreateInterface", []interface{}{map[string]dbus.Variant{"Ifname": dbus.MakeVariant("wl1"), "ConfigFile": dbus.MakeVariant("/etc/wl1.conf")}}, ""},
+		{"GetInterface", []interface{}{"wl1"}, ""},
+		{"RemoveInterface", []interface{}{path}, ""},
+		{"RemoveInterface", []interface{}{path}, ErrInterfaceUnknown},
+		{"GetInterface", []interface{}{"wl0"}, ErrInterfaceUnknown},
+	}
+	for _, tt := range tests {
+		if err := root.Call(RootIface+"."+tt.method, 0, tt.args...).Err; errorName(err) != tt.err {
+			t.Errorf("%s%v: error %v, want %q", tt.method, tt.args, err, tt.err)
+		}
+	}
+	if s.Interface("wl0") != nil || s.Interface("wl1") == nil {
+		t.Error("interfaces not updated")
+	}
+	if config := s.Interface("wl1").Property("ConfigFile"); config != "/etc/wl1.conf" {
+		t.Errorf("ConfigFile %v", config)
+	}
+	ifaces, err := root.GetProperty(RootIface + ".Interfaces")
+	if err != nil || len(ifaces.Value().([]dbus.ObjectPath)) != 1 {
+		t.Errorf("Interfaces %v %v", ifaces, err)
+	}
+}
+
+func TestProperties(t *testing.T) {
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{Ifname: "wl0"}}})
+	obj := client.Object(Service, s.Interface("wl0").Path())
+	tests := []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"Country", "DE", ""},
+		{"ApScan", uint32(2), ""},
+		{"ApScan", int32(2), ErrInvalidArgs},
+		{"State", "completed", ErrInvalidArgs},
+		{"NoSuchProperty", "x", ErrInvalidArgs},
+	}
+	for _, tt := range tests {
+		if err := obj.SetProperty(InterfaceIface+"."+tt.name, dbus.MakeVariant(tt.value)); errorName(err) != tt.err {
+			t.Errorf("set %s to %v: error %v, want %q", tt.name, tt.value, err, tt.err)
+		}
+	}
+	if country := s.Interface("wl0").Property("Country"); country != "DE" {
+		t.Errorf("Country %v", country)
+	}
+	var all map[string]dbus.Variant
+	if err := obj.Call(propertiesIface+".GetAll", 0, InterfaceIface).Store(&all); err != nil || all["ApScan"].Value() != uint32(2) {
+		t.Errorf("GetAll %v %v", all["ApScan"], err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	office := BSSConfig{SSID: "Office", BSSID: "00:11:22:33:44:55", Frequency: 2412, Signal: -48, KeyMgmt: []string{"wpa-psk"}}
+	guest := BSSConfig{SSID: "Guest", BSSID: "00:11:22:33:44:66", Frequency: 5180, Signal: -70}
+	closer := office
+	closer.Signal = -40
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{
+		Ifname:      "wl0",
+		BSSs:        []BSSConfig{office},
+		ScanResults: []BSSConfig{guest, closer},
+	}}})
+	i := s.Interface("wl0")
+	obj := client.Object(Service, i.Path())
+	ch := signals(t, client, i.Path(), InterfaceIface)
+	kept := i.BSSs()[0].Path()
+
+	for _, args := range []map[string]dbus.Variant{
+		{},
+		{"Type": dbus.MakeVariant("fast")},
+		{"Type": dbus.MakeVariant("active"), "SSIDs": dbus.MakeVariant([]string{"Office"})},
+		{"Type": dbus.MakeVariant("active"), "Bogus": dbus.MakeVariant(true)},
+	} {
+		if err := obj.Call(InterfaceIface+".Scan", 0, args).Err; errorName(err) != ErrInvalidArgs {
+			t.Errorf("Scan(%v): error %v, want %s", args, err, ErrInvalidArgs)
+		}
+	}
+	if n := len(i.ScanArgs()); n != 0 {
+		t.Errorf("%d invalid scans recorded", n)
+	}
+
+	args := map[string]dbus.Variant{"Type": dbus.MakeVariant("active"), "SSIDs": dbus.MakeVariant([][]byte{[]byte("Office")})}
+	if err := obj.Call(InterfaceIface+".Scan", 0, args).Err; err != nil {
+		t.Fatal(err)
+	}
+	if done := waitSignal(t, ch, "ScanDone"); done.Body[0] != true {
+		t.Errorf("ScanDone %v", done.Body)
+	}
+	bsss := i.BSSs()
+	if len(bsss) != 2 || bsss[0].Path() != kept || bsss[1].Config().SSID != "Guest" {
+		t.Errorf("BSSs after scan %v", bsss)
+	}
+	if b := bsss[0].Config(); b.Signal != -40 {
+		t.Errorf("BSS not updated: %+v", b)
+	}
+	if len(i.ScanArgs()) != 1 {
+		t.Errorf("scans %v", i.ScanArgs())
+	}
+
+	/* A running scan rejects another one and can be aborted */
+	i.SetScanDuration(-1)
+	if err := obj.Call(InterfaceIface+".Scan", 0, args).Err; err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Call(InterfaceIface+".Scan", 0, args).Err; errorName(err) != ErrScanError {
+		t.Errorf("second Scan: error %v, want %s", err, ErrScanError)
+	}
+	if err := obj.Call(InterfaceIface+".AbortScan", 0).Err; err != nil {
+		t.Fatal(err)
+	}
+	if done := waitSignal(t, ch, "ScanDone"); done.Body[0] != false {
+		t.Errorf("ScanDone after abort %v", done.Body)
+	}
+	if err := obj.Call(InterfaceIface+".AbortScan", 0).Err; errorName(err) != ErrUnknownError {
+		t.Errorf("AbortScan without scan: error %v", err)
+	}
+
+	/* A failed scan keeps the BSSs */
+	i.SetScanDuration(time.Millisecond)
+	i.SetScanFailure(true)
+	i.SetScanResults()
+	if err := obj.Call(InterfaceIface+".Scan", 0, args).Err; err != nil {
+		t.Fatal(err)
+	}
+	if done := waitSignal(t, ch, "ScanDone"); done.Body[0] != false {
+		t.Errorf("ScanDone of failed scan %v", done.Body)
+	}
+	if n := len(i.BSSs()); n != 2 {
+		t.Errorf("%d BSSs after failed scan", n)
+	}
+
+	if err := obj.Call(InterfaceIface+".FlushBSS", 0, uint32(0)).Err; err != nil || len(i.BSSs()) != 0 {
+		t.Errorf("FlushBSS: %v, %d BSSs left", err, len(i.BSSs()))
+	}
+}
+
+func TestAutoScan(t *testing.T) {
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{Ifname: "wl0"}}})
+	i := s.Interface("wl0")
+	obj := client.Object(Service, i.Path())
+	tests := []struct {
+		arg  string
+		err  string
+		want string
+	}{
+		{"exponential:3:300", "", "exponential:3:300"},
+		{"periodic:30", "", "periodic:30"},
+		{"sometimes", ErrUnknownError, "periodic:30"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if err := obj.Call(InterfaceIface+".AutoScan", 0, tt.arg).Err; errorName(err) != tt.err {
+			t.Errorf("AutoScan(%q): error %v, want %q", tt.arg, err, tt.err)
+		}
+		if got := i.AutoScanArg(); got != tt.want {
+			t.Errorf("AutoScan(%q): autoscan %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{
+		Ifname:   "wl0",
+		Networks: []map[string]string{{"ssid": `"Office"`, "disabled": "0"}},
+	}}})
+	i := s.Interface("wl0")
+	obj := client.Object(Service, i.Path())
+	args := map[string]dbus.Variant{
+		"ssid":      dbus.MakeVariant("Home"),
+		"psk":       dbus.MakeVariant([]byte{0x01, 0xab}),
+		"key_mgmt":  dbus.MakeVariant("SAE"),
+		"priority":  dbus.MakeVariant(int32(5)),
+		"scan_ssid": dbus.MakeVariant("1"),
+		"identity":  dbus.MakeVariant(`a"b\c ä`),
+	}
+	var path dbus.ObjectPath
+	if err := obj.Call(InterfaceIface+".AddNetwork", 0, args).Store(&path); err != nil {
+		t.Fatal(err)
+	}
+	networks := i.Networks()
+	if len(networks) != 2 || networks[1].Path() != path {
+		t.Fatalf("networks %v", networks)
+	}
+	want := map[string]string{"ssid": `"Home"`, "psk": "01ab", "key_mgmt": "SAE", "priority": "5", "scan_ssid": "1", "disabled": "1", "identity": `"a"b\c ä"`}
+	fields := networks[1].Fields()
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("field %s is %q, want %q", key, fields[key], value)
+		}
+	}
+	if err := obj.Call(InterfaceIface+".AddNetwork", 0, map[string]dbus.Variant{"ssid": dbus.MakeVariant(true)}).Err; errorName(err) != ErrInvalidArgs {
+		t.Errorf("AddNetwork with bool: error %v", err)
+	}
+
+	nw := client.Object(Service, path)
+	if err := nw.SetProperty(NetworkIface+".Properties", dbus.MakeVariant(map[string]dbus.Variant{"priority": dbus.MakeVariant("7")})); err != nil {
+		t.Fatal(err)
+	}
+	if p := i.Networks()[1].Fields()["priority"]; p != `"7"` {
+		t.Errorf("priority %q after Set", p)
+	}
+	if err := nw.SetProperty(NetworkIface+".Enabled", dbus.MakeVariant(true)); err != nil || i.Networks()[1].Fields()["disabled"] != "0" {
+		t.Errorf("Enabled: %v, fields %v", err, i.Networks()[1].Fields())
+	}
+
+	for _, tt := range []struct {
+		method string
+		arg    dbus.ObjectPath
+		err    string
+	}{
+		{"RemoveNetwork", "/foo", ErrInvalidArgs},
+		{"RemoveNetwork", i.Path() + "/Networks/9", ErrNetworkUnknown},
+		{"SelectNetwork", i.Path() + "/Networks/9", ErrNetworkUnknown},
+		{"RemoveNetwork", path, ""},
+	} {
+		if err := obj.Call(InterfaceIface+"."+tt.method, 0, tt.arg).Err; errorName(err) != tt.err {
+			t.Errorf("%s(%s): error %v, want %q", tt.method, tt.arg, err, tt.err)
+		}
+	}
+	if err := obj.Call(InterfaceIface+".RemoveAllNetworks", 0).Err; err != nil || len(i.Networks()) != 0 {
+		t.Errorf("RemoveAllNetworks: %v, %d networks left", err, len(i.Networks()))
+	}
+}
+
+func TestConnect(t *testing.T) {
+	office := BSSConfig{SSID: "Office", BSSID: "00:11:22:33:44:55", Frequency: 2412, Signal: -48, KeyMgmt: []string{"wpa-psk"}}
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{
+		Ifname:   "wl0",
+		BSSs:     []BSSConfig{office},
+		Networks: []map[string]string{{"ssid": `"Office"`, "key_mgmt": "WPA-PSK", "disabled": "0"}},
+	}}})
+	i := s.Interface("wl0")
+	obj := client.Object(Service, i.Path())
+	ch := signals(t, client, i.Path(), InterfaceIface)
+	if err := obj.Call(InterfaceIface+".Disconnect", 0).Err; errorName(err) != ErrNotConnected {
+		t.Errorf("Disconnect: error %v", err)
+	}
+	if err := obj.Call(InterfaceIface+".SelectNetwork", 0, i.Networks()[0].Path()).Err; err != nil {
+		t.Fatal(err)
+	}
+	waitSignal(t, ch, "NetworkSelected")
+	waitState(t, i, "completed")
+	if bss := i.Property("CurrentBSS"); bss != i.BSSs()[0].Path() {
+		t.Errorf("CurrentBSS %v", bss)
+	}
+	if err := obj.Call(InterfaceIface+".Disconnect", 0).Err; err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, i, "disconnected")
+
+	i.SetAuthFailure(15)
+	if err := obj.Call(InterfaceIface+".Reconnect", 0).Err; err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if state := i.Property("State"); state == "completed" {
+		t.Error("connected despite the authentication failure")
+	}
+}
+
+// waitState polls the state of the interface
+func waitState(t *testing.T, i *Interface, state string) {
+	t.Helper()
+	for n := 0; n < 100; n++ {
+		if i.Property("State") == state {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("state %v, want %s", i.Property("State"), state)
+}
+
+func TestBlobs(t *testing.T) {
+	s, client := start(t, Scenario{Interfaces: []InterfaceScenario{{Ifname: "wl0", Blobs: map[string][]byte{"ca": []byte("hello")}}}})
+	i := s.Interface("wl0")
+	obj := client.Object(Service, i.Path())
+	var data []byte
+	if err := obj.Call(InterfaceIface+".GetBlob", 0, "ca").Store(&data); err != nil || string(data) != "hello" {
+		t.Errorf("GetBlob: %q %v", data, err)
+	}
+	tests := []struct {
+		method string
+		args   []interface{}
+		err    string
+	}{
+		{"AddBlob", []interface{}{"ca", []byte("x")}, ErrBlobExists},
+		{"AddBlob", []interface{}{"key", []byte("secret")}, ""},
+		{"GetBlob", []interface{}{"none"}, ErrBlobUnknown},
+		{"RemoveBlob", []interface{}{"ca"}, ""},
+		{"RemoveBlob", []interface{}{"ca"}, ErrBlobUnknown},
+	}
+	for _, tt := range tests {
+		if err := obj.Call(InterfaceIface+"."+tt.method, 0, tt.args...).Err; errorName(err) != tt.err {
+			t.Errorf("%s(%v): error %v, want %q", tt.method, tt.args[0], err, tt.err)
+		}
+	}
+	if blobs := i.Blobs(); len(blobs) != 1 || string(blobs["key"]) != "secret" {
+		t.Errorf("blobs %v", blobs)
+	}
+	var prop map[string][]byte
+	if v, err := obj.GetProperty(InterfaceIface + ".Blobs"); err != nil || v.Store(&prop) != nil || len(prop) != 1 {
+		t.Errorf("Blobs property %v %v", v, err)
+	}
+}
+
+func TestScriptedFailures(t *testing.T) {
+	s, client := start(t, Scenario{
+		Interfaces: []InterfaceScenario{{Ifname: "wl0", Fail: map[string]string{"Scan": ErrAccessDenied, "Get": ErrUnknownError}}},
+		Fail:       map[string]string{"CreateInterface": ErrInvalidArgs},
+	})
+	i := s.Interface("wl0")
+	obj := client.Object(Service, i.Path())
+	if err := obj.Call(InterfaceIface+".Scan", 0, map[string]dbus.Variant{"Type": dbus.MakeVariant("active")}).Err; errorName(err) != ErrAccessDenied {
+		t.Errorf("Scan: error %v", err)
+	}
+	if _, err := obj.GetProperty(InterfaceIface + ".State"); errorName(err) != ErrUnknownError {
+		t.Errorf("Get: error %v", err)
+	}
+	root := client.Object(Service, Path)
+	if err := root.Call(RootIface+".CreateInterface", 0, map[string]dbus.Variant{"Ifname": dbus.MakeVariant("wl1")}).Err; errorName(err) != ErrInvalidArgs {
+		t.Errorf("CreateInterface: error %v", err)
+	}
+	/* Removing the failure restores the normal behavior */
+	i.Fail("Get", nil)
+	if state, err := obj.GetProperty(InterfaceIface + ".State"); err != nil || state.Value() != "disconnected" {
+		t.Errorf("State %v %v", state, err)
+	}
+}
@@ -0,0 +1,731 @@
+package fake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Interface is a fake managed network interface
+type Interface struct {
+	object
+	ifname       string
+	bsss         []*BSS
+	networks     []*Network
+	blobs        map[string][]byte
+	nextBSS      int
+	nextNetwork  int
+	scanResults  []BSSConfig
+	scanFails    bool
+	scanDuration time.Duration
+	scanTimer    *time.Timer
+	scanArgs     []map[string]dbus.Variant
+	authFailure  int32
+	connectStep  time.Duration
+	signalPoll   map[string]dbus.Variant
+	autoScan     string
+	saveCount    int
+}
+
+func newInterface(s *Supplicant, path dbus.ObjectPath, ifname, driver, config, bridge string) *Interface {
+	i := &Interface{
+		object:       newObject(s, path, InterfaceIface),
+		ifname:       ifname,
+		blobs:        make(map[string][]byte),
+		scanDuration: 20 * time.Millisecond,
+		connectStep:  10 * time.Millisecond,
+	}
+	if len(driver) == 0 {
+		driver = "nl80211"
+	}
+	i.prop("Ifname", ifname, false)
+	i.prop("Driver", driver, false)
+	i.prop("BridgeIfname", bridge, false)
+	i.prop("ConfigFile", config, false)
+	i.prop("State", "disconnected", false)
+	i.prop("Scanning", false, false)
+	i.prop("ApScan", uint32(1), true)
+	i.prop("BSSExpireAge", uint32(180), true)
+	i.prop("BSSExpireCount", uint32(2), true)
+	i.prop("Country", "", true)
+	i.prop("FastReauth", true, true)
+	i.prop("ScanInterval", int32(5), true)
+	i.prop("PKCS11EnginePath", "", false)
+	i.prop("PKCS11ModulePath", "", false)
+	i.prop("CurrentBSS", noObjectPath, false)
+	i.prop("CurrentNetwork", noObjectPath, false)
+	i.prop("CurrentAuthMode", "INACTIVE", false)
+	i.prop("Blobs", map[string][]byte{}, false)
+	i.prop("BSSs", []dbus.ObjectPath{}, false)
+	i.prop("Networks", []dbus.ObjectPath{}, false)
+	i.prop("DisconnectReason", int32(0), false)
+	i.prop("AuthStatusCode", int32(0), false)
+	i.prop("AssocStatusCode", int32(0), false)
+	i.prop("RoamTime", uint32(0), false)
+	i.prop("RoamComplete", false, false)
+	i.prop("SessionLength", uint32(0), false)
+	i.prop("BSSTMStatus", uint32(0), false)
+	i.prop("Stations", []dbus.ObjectPath{}, false)
+	i.prop("MACAddressRandomizationMask", map[string][]byte{}, true)
+	i.prop("Capabilities", map[string]dbus.Variant{
+		"Pairwise":    dbus.MakeVariant([]string{"ccmp-256", "gcmp-256", "ccmp", "gcmp", "tkip", "none"}),
+		"Group":       dbus.MakeVariant([]string{"ccmp-256", "gcmp-256", "ccmp", "gcmp", "tkip"}),
+		"GroupMgmt":   dbus.MakeVariant([]string{"aes-128-cmac", "bip-gmac-128", "bip-gmac-256", "bip-cmac-256"}),
+		"KeyMgmt":     dbus.MakeVariant([]string{"none", "ieee8021x", "wpa-eap", "wpa-psk", "wpa-eap-suite-b", "wpa-none", "sae", "owe"}),
+		"Protocol":    dbus.MakeVariant([]string{"rsn", "wpa"}),
+		"AuthAlg":     dbus.MakeVariant([]string{"open", "shared", "leap"}),
+		"Scan":        dbus.MakeVariant([]string{"active", "passive", "ssid"}),
+		"Modes":       dbus.MakeVariant([]string{"infrastructure", "ad-hoc", "ap", "p2p", "mesh"}),
+		"MaxScanSSID": dbus.MakeVariant(int32(4)),
+	}, false)
+	i.methods = map[string]interface{}{
+		"Scan":              i.scan,
+		"AbortScan":         i.abortScan,
+		"AutoScan":          i.autoScanMethod,
+		"SignalPoll":        i.signalPollMethod,
+		"Disconnect":        i.disconnect,
+		"Reassociate":       i.reassociate,
+		"Reattach":          i.reattach,
+		"Reconnect":         i.reconnect,
+		"AddNetwork":        i.addNetwork,
+		"RemoveNetwork":     i.removeNetwork,
+		"RemoveAllNetworks": i.removeAllNetworks,
+		"SelectNetwork":     i.selectNetwork,
+		"AddBlob":           i.addBlob,
+		"GetBlob":           i.getBlob,
+		"RemoveBlob":        i.removeBlob,
+		"FlushBSS":          i.flushBSS,
+		"SaveConfig":        i.saveConfig,
+	}
+	return i
+}
+
+// Path returns the D-Bus object path of the interface
+func (i *Interface) Path() dbus.ObjectPath {
+	return i.path
+}
+
+func (i *Interface) remove() {
+	if i.scanTimer != nil {
+		i.scanTimer.Stop()
+	}
+	for _, b := range i.bsss {
+		b.unexport()
+	}
+	for _, n := range i.networks {
+		n.unexport()
+	}
+	i.unexport()
+}
+
+// SetScanResults defines the BSSs which the next scans will report
+func (i *Interface) SetScanResults(bsss ...BSSConfig) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	i.scanResults = bsss
+}
+
+// SetScanFailure makes following scans complete with ScanDone(false)
+func (i *Interface) SetScanFailure(fail bool) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	i.scanFails = fail
+}
+
+// SetScanDuration sets the time between a Scan call and ScanDone. A
+// negative duration lets scans never complete.
+func (i *Interface) SetScanDuration(d time.Duration) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	i.scanDuration = d
+}
+
+// ScanArgs returns the arguments of all Scan calls received so far
+func (i *Interface) ScanArgs() []map[string]dbus.Variant {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return append([]map[string]dbus.Variant(nil), i.scanArgs...)
+}
+
+// SetAuthFailure makes following connection attempts fail with the given
+// IEEE 802.11 reason code. A reason of 0 lets them succeed again.
+func (i *Interface) SetAuthFailure(reason int32) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	i.authFailure = reason
+}
+
+// SetSignalPoll defines the reply of SignalPoll while connected
+func (i *Interface) SetSignalPoll(info map[string]dbus.Variant) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	i.signalPoll = info
+}
+
+// AutoScanArg returns the argument of the last successful AutoScan call
+func (i *Interface) AutoScanArg() string {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return i.autoScan
+}
+
+// SaveCount returns how often the configuration was saved
+func (i *Interface) SaveCount() int {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return i.saveCount
+}
+
+// SetState changes the connection state
+func (i *Interface) SetState(state string) {
+	i.SetProperty("State", state)
+}
+
+// AddBSS adds a BSS to the scan results right away
+func (i *Interface) AddBSS(cfg BSSConfig) *BSS {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return i.addBSS(cfg)
+}
+
+// RemoveBSS removes a BSS from the scan results right away
+func (i *Interface) RemoveBSS(b *BSS) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	i.removeBSS(b)
+}
+
+// BSSs returns the current scan results
+func (i *Interface) BSSs() []*BSS {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return append([]*BSS(nil), i.bsss...)
+}
+
+// AddNetwork adds a network as if it was read from the configuration file.
+// Values are given in wpa_supplicant.conf syntax.
+func (i *Interface) AddNetwork(props map[string]string) *Network {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return i.newNetwork(props)
+}
+
+// Networks returns the configured networks
+func (i *Interface) Networks() []*Network {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	return append([]*Network(nil), i.networks...)
+}
+
+// Blobs returns a copy of the blobs of the interface
+func (i *Interface) Blobs() map[string][]byte {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	blobs := make(map[string][]byte, len(i.blobs))
+	for name, data := range i.blobs {
+		blobs[name] = data
+	}
+	return blobs
+}
+
+func (i *Interface) addBSS(cfg BSSConfig) *BSS {
+	path := dbus.ObjectPath(fmt.Sprintf("%s/BSSs/%d", i.path, i.nextBSS))
+	i.nextBSS++
+	b := newBSS(i.s, path, cfg)
+	b.export()
+	i.bsss = append(i.bsss, b)
+	i.updateBSSs()
+	i.emit("BSSAdded", path, b.properties())
+	return b
+}
+
+func (i *Interface) removeBSS(b *BSS) {
+	for idx, old := range i.bsss {
+		if old == b {
+			i.bsss = append(i.bsss[:idx], i.bsss[idx+1:]...)
+			b.unexport()
+			if i.get("CurrentBSS").(dbus.ObjectPath) == b.path {
+				i.setProperty("CurrentBSS", noObjectPath)
+			}
+			i.updateBSSs()
+			i.emit("BSSRemoved", b.path)
+			return
+		}
+	}
+}
+
+func (i *Interface) updateBSSs() {
+	paths := make([]dbus.ObjectPath, 0, len(i.bsss))
+	for _, b := range i.bsss {
+		paths = append(paths, b.path)
+	}
+	i.setProperty("BSSs", paths)
+}
+
+func (i *Interface) updateNetworks() {
+	paths := make([]dbus.ObjectPath, 0, len(i.networks))
+	for _, n := range i.networks {
+		paths = append(paths, n.path)
+	}
+	i.setProperty("Networks", paths)
+}
+
+func (i *Interface) updateBlobs() {
+	blobs := make(map[string][]byte, len(i.blobs))
+	for name, data := range i.blobs {
+		blobs[name] = data
+	}
+	i.setProperty("Blobs", blobs)
+}
+
+func (i *Interface) scan(args map[string]dbus.Variant) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("Scan"); err != nil {
+		return err
+	}
+	for key, v := range args {
+		switch key {
+		case "Type":
+			if t, _ := v.Value().(string); t != "active" && t != "passive" {
+				return NewError(ErrInvalidArgs, "Wrong scan type: "+v.String())
+			}
+		case "AllowRoam":
+			if _, ok := v.Value().(bool); !ok {
+				return NewError(ErrInvalidArgs, "Wrong AllowRoam value type. Boolean required")
+			}
+		case "SSIDs", "IEs":
+			if _, ok := v.Value().([][]byte); !ok {
+				return NewError(ErrInvalidArgs, "Wrong "+key+" value type. Array of arrays of bytes required")
+			}
+		case "Channels":
+			if _, ok := v.Value().([][]interface{}); !ok {
+				return NewError(ErrInvalidArgs, "Wrong Channels value type. Array of structs required")
+			}
+		default:
+			return NewError(ErrInvalidArgs, "Unsupported argument "+key)
+		}
+	}
+	if _, ok := args["Type"]; !ok {
+		return NewError(ErrInvalidArgs, "Scan type not specified")
+	}
+	if i.get("Scanning").(bool) {
+		return NewError(ErrScanError, "Scan request rejected")
+	}
+	i.scanArgs = append(i.scanArgs, args)
+	i.setProperty("Scanning", true)
+	if i.scanDuration >= 0 {
+		i.scanTimer = time.AfterFunc(i.scanDuration, i.scanDone)
+	}
+	return nil
+}
+
+func (i *Interface) scanDone() {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if !i.get("Scanning").(bool) {
+		return
+	}
+	i.scanTimer = nil
+	if !i.scanFails {
+		i.applyScanResults()
+	}
+	i.setProperty("Scanning", false)
+	i.emit("ScanDone", !i.scanFails)
+}
+
+// applyScanResults replaces the BSS list by the scripted scan results.
+// BSSs with an unchanged BSSID keep their object path.
+func (i *Interface) applyScanResults() {
+	want := make(map[string]BSSConfig)
+	for _, cfg := range i.scanResults {
+		want[strings.ToLower(cfg.BSSID)] = cfg
+	}
+	for _, b := range append([]*BSS(nil), i.bsss...) {
+		if cfg, ok := want[strings.ToLower(b.cfg.BSSID)]; ok {
+			b.update(cfg)
+			delete(want, strings.ToLower(b.cfg.BSSID))
+		} else {
+			i.removeBSS(b)
+		}
+	}
+	for _, cfg := range i.scanResults {
+		if _, ok := want[strings.ToLower(cfg.BSSID)]; ok {
+			i.addBSS(cfg)
+		}
+	}
+}
+
+func (i *Interface) abortScan() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("AbortScan"); err != nil {
+		return err
+	}
+	if !i.get("Scanning").(bool) {
+		return NewError(ErrUnknownError, "Abort failed or no scan in progress")
+	}
+	if i.scanTimer != nil {
+		i.scanTimer.Stop()
+		i.scanTimer = nil
+	}
+	i.setProperty("Scanning", false)
+	i.emit("ScanDone", false)
+	return nil
+}
+
+func (i *Interface) autoScanMethod(arg string) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("AutoScan"); err != nil {
+		return err
+	}
+	if len(arg) > 0 && !strings.HasPrefix(arg, "exponential:") && !strings.HasPrefix(arg, "periodic:") {
+		return NewError(ErrUnknownError, "wpa_supplicant could not set autoscan mode")
+	}
+	i.autoScan = arg
+	return nil
+}
+
+func (i *Interface) signalPollMethod() (map[string]dbus.Variant, *dbus.Error) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("SignalPoll"); err != nil {
+		return nil, err
+	}
+	if i.get("State").(string) != "completed" {
+		return nil, NewError(ErrUnknownError, "Failed to read signal")
+	}
+	if i.signalPoll != nil {
+		return i.signalPoll, nil
+	}
+	info := map[string]dbus.Variant{
+		"linkspeed": dbus.MakeVariant(int32(65)),
+		"noise":     dbus.MakeVariant(int32(-95)),
+		"width":     dbus.MakeVariant("20 MHz"),
+	}
+	if b := i.currentBSS(); b != nil {
+		info["rssi"] = dbus.MakeVariant(int32(b.cfg.Signal))
+		info["frequency"] = dbus.MakeVariant(uint32(b.cfg.Frequency))
+		info["center-frq1"] = dbus.MakeVariant(int32(b.cfg.Frequency))
+	}
+	return info, nil
+}
+
+func (i *Interface) currentBSS() *BSS {
+	p := i.get("CurrentBSS").(dbus.ObjectPath)
+	for _, b := range i.bsss {
+		if b.path == p {
+			return b
+		}
+	}
+	return nil
+}
+
+func (i *Interface) setDisconnected(reason int32) {
+	i.props["CurrentBSS"].value = noObjectPath
+	i.props["CurrentNetwork"].value = noObjectPath
+	i.props["CurrentAuthMode"].value = "INACTIVE"
+	i.props["DisconnectReason"].value = reason
+	i.props["State"].value = "disconnected"
+	i.emitChanged("CurrentBSS", "CurrentNetwork", "CurrentAuthMode", "DisconnectReason", "State")
+}
+
+func (i *Interface) disconnect() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("Disconnect"); err != nil {
+		return err
+	}
+	if i.get("State").(string) == "disconnected" {
+		return NewError(ErrNotConnected, "This interface is not connected")
+	}
+	i.setDisconnected(-3)
+	return nil
+}
+
+func (i *Interface) reassociate() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("Reassociate"); err != nil {
+		return err
+	}
+	if i.get("State").(string) == "interface_disabled" {
+		return NewError(ErrInterfaceDisabled, "This interface is disabled")
+	}
+	i.connect(nil)
+	return nil
+}
+
+func (i *Interface) reattach() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("Reattach"); err != nil {
+		return err
+	}
+	if i.get("State").(string) != "completed" {
+		return NewError(ErrNotConnected, "This interface is not connected")
+	}
+	i.connect(i.networkByPath(i.get("CurrentNetwork").(dbus.ObjectPath)))
+	return nil
+}
+
+func (i *Interface) reconnect() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("Reconnect"); err != nil {
+		return err
+	}
+	if i.get("State").(string) != "disconnected" {
+		return NewError(ErrNotConnected, "This interface is not connected")
+	}
+	i.connect(nil)
+	return nil
+}
+
+// connect runs through the association states towards "completed". The
+// network n or, if nil, the enabled network with the highest priority
+// is matched against the BSSs by SSID.
+func (i *Interface) connect(n *Network) {
+	if n == nil {
+		for _, cand := range i.networks {
+			if cand.enabled() && (n == nil || cand.priority() > n.priority()) {
+				n = cand
+			}
+		}
+	}
+	var bss *BSS
+	if n != nil {
+		for _, b := range i.bsss {
+			if n.matches(b) && (bss == nil || b.cfg.Signal > bss.cfg.Signal) {
+				bss = b
+			}
+		}
+	}
+	if bss == nil {
+		i.setProperty("State", "scanning")
+		return
+	}
+	states := []string{"associating", "associated", "4way_handshake", "completed"}
+	reason := i.authFailure
+	if reason != 0 {
+		states = []string{"associating", "associated", "4way_handshake", "disconnected"}
+	}
+	var step func(idx int)
+	step = func(idx int) {
+		i.s.mu.Lock()
+		defer i.s.mu.Unlock()
+		if _, ok := i.s.ifaces[i.ifname]; !ok {
+			return
+		}
+		switch state := states[idx]; state {
+		case "associated":
+			i.props["CurrentBSS"].value = bss.path
+			i.props["CurrentNetwork"].value = n.path
+			i.props["State"].value = state
+			i.emitChanged("CurrentBSS", "CurrentNetwork", "State")
+		case "completed":
+			i.props["CurrentAuthMode"].value = n.authMode()
+			i.props["DisconnectReason"].value = int32(0)
+			i.props["State"].value = state
+			i.emitChanged("CurrentAuthMode", "DisconnectReason", "State")
+		case "disconnected":
+			i.setDisconnected(reason)
+		default:
+			i.setProperty("State", state)
+		}
+		if idx+1 < len(states) {
+			time.AfterFunc(i.connectStep, func() { step(idx + 1) })
+		}
+	}
+	time.AfterFunc(i.connectStep, func() { step(0) })
+}
+
+func (i *Interface) networkByPath(path dbus.ObjectPath) *Network {
+	for _, n := range i.networks {
+		if n.path == path {
+			return n
+		}
+	}
+	return nil
+}
+
+func (i *Interface) newNetwork(props map[string]string) *Network {
+	path := dbus.ObjectPath(fmt.Sprintf("%s/Networks/%d", i.path, i.nextNetwork))
+	i.nextNetwork++
+	n := newNetwork(i, path, props)
+	n.export()
+	i.networks = append(i.networks, n)
+	i.updateNetworks()
+	i.emit("NetworkAdded", path, n.properties())
+	return n
+}
+
+func (i *Interface) addNetwork(args map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("AddNetwork"); err != nil {
+		return noObjectPath, err
+	}
+	props := map[string]string{"disabled": "1", "priority": "0", "key_mgmt": "WPA-PSK WPA-EAP"}
+	if err := convertNetworkArgs(args, props); err != nil {
+		return noObjectPath, err
+	}
+	return i.newNetwork(props).path, nil
+}
+
+func (i *Interface) removeNetworkLocked(n *Network) {
+	for idx, old := range i.networks {
+		if old == n {
+			i.networks = append(i.networks[:idx], i.networks[idx+1:]...)
+			break
+		}
+	}
+	n.unexport()
+	if i.get("CurrentNetwork").(dbus.ObjectPath) == n.path {
+		i.setDisconnected(-3)
+	}
+	i.updateNetworks()
+	i.emit("NetworkRemoved", n.path)
+}
+
+func (i *Interface) removeNetwork(path dbus.ObjectPath) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("RemoveNetwork"); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(string(path), string(i.path)+"/Networks/") {
+		return NewError(ErrInvalidArgs, "Invalid object path")
+	}
+	n := i.networkByPath(path)
+	if n == nil {
+		return NewError(ErrNetworkUnknown, "There is no such a network in this interface.")
+	}
+	i.removeNetworkLocked(n)
+	return nil
+}
+
+func (i *Interface) removeAllNetworks() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("RemoveAllNetworks"); err != nil {
+		return err
+	}
+	for _, n := range append([]*Network(nil), i.networks...) {
+		i.removeNetworkLocked(n)
+	}
+	return nil
+}
+
+func (i *Interface) selectNetwork(path dbus.ObjectPath) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("SelectNetwork"); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(string(path), string(i.path)+"/Networks/") {
+		return NewError(ErrInvalidArgs, "Invalid object path")
+	}
+	n := i.networkByPath(path)
+	if n == nil {
+		return NewError(ErrNetworkUnknown, "There is no such a network in this interface.")
+	}
+	for _, other := range i.networks {
+		other.setEnabled(other == n)
+	}
+	i.emit("NetworkSelected", path)
+	i.connect(n)
+	return nil
+}
+
+func (i *Interface) addBlob(name string, data []byte) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("AddBlob"); err != nil {
+		return err
+	}
+	if _, ok := i.blobs[name]; ok {
+		return NewError(ErrBlobExists, "Blob with given name exists")
+	}
+	i.blobs[name] = data
+	i.updateBlobs()
+	i.emit("BlobAdded", name)
+	return nil
+}
+
+func (i *Interface) getBlob(name string) ([]byte, *dbus.Error) {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("GetBlob"); err != nil {
+		return nil, err
+	}
+	data, ok := i.blobs[name]
+	if !ok {
+		return nil, NewError(ErrBlobUnknown, "Blob id not set")
+	}
+	return data, nil
+}
+
+func (i *Interface) removeBlob(name string) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("RemoveBlob"); err != nil {
+		return err
+	}
+	if _, ok := i.blobs[name]; !ok {
+		return NewError(ErrBlobUnknown, "Blob id not set")
+	}
+	delete(i.blobs, name)
+	i.updateBlobs()
+	i.emit("BlobRemoved", name)
+	return nil
+}
+
+func (i *Interface) flushBSS(age uint32) *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("FlushBSS"); err != nil {
+		return err
+	}
+	for _, b := range append([]*BSS(nil), i.bsss...) {
+		if age == 0 || b.get("Age").(uint32) > age {
+			i.removeBSS(b)
+		}
+	}
+	return nil
+}
+
+func (i *Interface) saveConfig() *dbus.Error {
+	i.s.mu.Lock()
+	defer i.s.mu.Unlock()
+	if err := i.fail("SaveConfig"); err != nil {
+		return err
+	}
+	config := i.get("ConfigFile").(string)
+	if len(config) == 0 {
+		return NewError(ErrUnknownError, "Not allowed to update configuration (update_config=0)")
+	}
+	var sb strings.Builder
+	for _, n := range i.networks {
+		sb.WriteString("\nnetwork={\n")
+		keys := make([]string, 0, len(n.fields))
+		for key := range n.fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "\t%s=%s\n", key, n.fields[key])
+		}
+		sb.WriteString("}\n")
+	}
+	if err := ioutil.WriteFile(config, []byte(sb.String()), 0600); err != nil {
+		return NewError(ErrUnknownError, "Failed to update configuration")
+	}
+	i.saveCount++
+	return nil
+}
@@ -0,0 +1,148 @@
+package fake
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// dontQuote lists the network fields which wpa_supplicant takes verbatim
+// from string arguments. All other string values are enclosed in quotes.
+var dontQuote = map[string]bool{
+	"key_mgmt": true, "proto": true, "pairwise": true, "auth_alg": true,
+	"group": true, "eap": true, "bssid": true, "scan_freq": true,
+	"freq_list": true, "scan_ssid": true, "bssid_hint": true,
+	"bssid_ignore": true, "bssid_accept": true, "bssid_blacklist": true,
+	"bssid_whitelist": true, "group_mgmt": true, "ignore_broadcast_ssid": true,
+	"mac_value": true,
+}
+
+// convertNetworkArgs turns AddNetwork arguments into wpa_supplicant.conf
+// values the way wpa_supplicant does
+func convertNetworkArgs(args map[string]dbus.Variant, fields map[string]string) *dbus.Error {
+	for key, v := range args {
+		switch val := v.Value().(type) {
+		case []byte:
+			fields[key] = hex.EncodeToString(val)
+		case string:
+			if dontQuote[key] {
+				fields[key] = val
+			} else {
+				/* Without escaping, as wpa_supplicant does */
+				fields[key] = `"` + val + `"`
+			}
+		case uint32:
+			fields[key] = strconv.FormatUint(uint64(val), 10)
+		case int32:
+			fields[key] = strconv.FormatInt(int64(val), 10)
+		default:
+			return NewError(ErrInvalidArgs, fmt.Sprintf("invalid message format: %s has type %s", key, v.Signature()))
+		}
+	}
+	return nil
+}
+
+// Network is a fake configured network block
+type Network struct {
+	object
+	iface  *Interface
+	fields map[string]string
+}
+
+func newNetwork(i *Interface, path dbus.ObjectPath, fields map[string]string) *Network {
+	n := &Network{object: newObject(i.s, path, NetworkIface), iface: i, fields: fields}
+	n.prop("Properties", n.variants(), true).set = n.setProperties
+	n.prop("Enabled", n.enabled(), true).set = func(v interface{}) *dbus.Error {
+		n.setEnabled(v.(bool))
+		return nil
+	}
+	return n
+}
+
+// Path returns the D-Bus object path of the network
+func (n *Network) Path() dbus.ObjectPath {
+	return n.path
+}
+
+// Fields returns a copy of the network block in wpa_supplicant.conf syntax
+func (n *Network) Fields() map[string]string {
+	n.s.mu.Lock()
+	defer n.s.mu.Unlock()
+	fields := make(map[string]string, len(n.fields))
+	for key, val := range n.fields {
+		fields[key] = val
+	}
+	return fields
+}
+
+func (n *Network) variants() map[string]dbus.Variant {
+	props := make(map[string]dbus.Variant, len(n.fields))
+	for key, val := range n.fields {
+		props[key] = dbus.MakeVariant(val)
+	}
+	return props
+}
+
+func (n *Network) setProperties(v interface{}) *dbus.Error {
+	if err := convertNetworkArgs(v.(map[string]dbus.Variant), n.fields); err != nil {
+		return err
+	}
+	n.props["Enabled"].value = n.enabled()
+	n.props["Properties"].value = n.variants()
+	n.emitChanged("Properties", "Enabled")
+	return nil
+}
+
+func (n *Network) enabled() bool {
+	return n.fields["disabled"] != "1"
+}
+
+func (n *Network) setEnabled(enabled bool) {
+	if enabled == n.enabled() {
+		return
+	}
+	n.fields["disabled"] = "1"
+	if enabled {
+		n.fields["disabled"] = "0"
+	}
+	n.props["Enabled"].value = enabled
+	n.props["Properties"].value = n.variants()
+	n.emitChanged("Properties", "Enabled")
+}
+
+func (n *Network) priority() int {
+	prio, _ := strconv.Atoi(n.fields["priority"])
+	return prio
+}
+
+// ssid returns the raw SSID of the network block
+func (n *Network) ssid() string {
+	ssid := n.fields["ssid"]
+	if len(ssid) >= 2 && strings.HasPrefix(ssid, `"`) && strings.HasSuffix(ssid, `"`) {
+		return ssid[1 : len(ssid)-1]
+	}
+	raw, _ := hex.DecodeString(ssid)
+	return string(raw)
+}
+
+func (n *Network) matches(b *BSS) bool {
+	if bssid, ok := n.fields["bssid"]; ok && !strings.EqualFold(bssid, b.cfg.BSSID) {
+		return false
+	}
+	return n.ssid() == b.cfg.SSID
+}
+
+func (n *Network) authMode() string {
+	switch km := n.fields["key_mgmt"]; {
+	case strings.Contains(km, "SAE"):
+		return "SAE"
+	case strings.Contains(km, "WPA-PSK"):
+		return "WPA2-PSK"
+	case strings.Contains(km, "WPA-EAP"), strings.Contains(km, "IEEE8021X"):
+		return "EAP-" + strings.Fields(n.fields["eap"] + " TLS")[0]
+	}
+	return "NONE"
+}
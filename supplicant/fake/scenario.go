@@ -0,0 +1,85 @@
+package fake
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Scenario describes the initial state of a fake supplicant
+type Scenario struct {
+	Interfaces []InterfaceScenario `json:"interfaces"`
+	// Fail maps root object methods to the D-Bus error name they return
+	Fail map[string]string `json:"fail,omitempty"`
+}
+
+// InterfaceScenario describes the initial state of a fake interface
+type InterfaceScenario struct {
+	Ifname     string `json:"ifname"`
+	State      string `json:"state,omitempty"`
+	ConfigFile string `json:"config_file,omitempty"`
+	// BSSs are known right away, ScanResults are reported by scans
+	BSSs        []BSSConfig `json:"bsss,omitempty"`
+	ScanResults []BSSConfig `json:"scan_results,omitempty"`
+	ScanFails   bool        `json:"scan_fails,omitempty"`
+	// ScanHangs lets scans never complete
+	ScanHangs   bool                `json:"scan_hangs,omitempty"`
+	AuthFailure int32               `json:"auth_failure,omitempty"`
+	Networks    []map[string]string `json:"networks,omitempty"`
+	Blobs       map[string][]byte   `json:"blobs,omitempty"`
+	// Fail maps interface methods to the D-Bus error name they return
+	Fail map[string]string `json:"fail,omitempty"`
+}
+
+// ReadScenario decodes a JSON scenario
+func ReadScenario(r io.Reader) (sc Scenario, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&sc)
+	return
+}
+
+func scriptedError(name string) *dbus.Error {
+	return NewError(name, "scripted failure")
+}
+
+// Load applies a scenario
+func (s *Supplicant) Load(sc Scenario) error {
+	for method, name := range sc.Fail {
+		s.Fail(method, scriptedError(name))
+	}
+	for _, is := range sc.Interfaces {
+		s.mu.Lock()
+		i, err := s.addInterface(is.Ifname, "", is.ConfigFile, "")
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		for _, cfg := range is.BSSs {
+			i.AddBSS(cfg)
+		}
+		for _, fields := range is.Networks {
+			i.AddNetwork(fields)
+		}
+		for name, data := range is.Blobs {
+			i.s.mu.Lock()
+			i.blobs[name] = data
+			i.updateBlobs()
+			i.s.mu.Unlock()
+		}
+		if len(is.State) > 0 {
+			i.SetState(is.State)
+		}
+		i.SetScanResults(is.ScanResults...)
+		i.SetScanFailure(is.ScanFails)
+		if is.ScanHangs {
+			i.SetScanDuration(-1)
+		}
+		i.SetAuthFailure(is.AuthFailure)
+		for method, name := range is.Fail {
+			i.Fail(method, scriptedError(name))
+		}
+	}
+	return nil
+}
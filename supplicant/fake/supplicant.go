@@ -0,0 +1,159 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Supplicant is the fake root object /fi/w1/wpa_supplicant1
+type Supplicant struct {
+	object
+	conn   *dbus.Conn
+	mu     sync.Mutex
+	ifaces map[string]*Interface
+	nextID int
+}
+
+// New exports a fake supplicant on conn and acquires the well-known name
+// fi.w1.wpa_supplicant1 on its bus.
+func New(conn *dbus.Conn) (*Supplicant, error) {
+	s := &Supplicant{conn: conn, ifaces: make(map[string]*Interface)}
+	s.object = newObject(s, Path, RootIface)
+	s.prop("Interfaces", []dbus.ObjectPath{}, false)
+	s.prop("DebugLevel", "info", true)
+	s.prop("DebugTimestamp", false, true)
+	s.prop("DebugShowKeys", false, true)
+	s.prop("EapMethods", []string{"MD5", "TLS", "MSCHAPV2", "PEAP", "TTLS", "GTC", "OTP", "PWD"}, false)
+	s.prop("Capabilities", []string{"ap", "ibss-rsn", "mesh"}, false)
+	s.prop("WFDIEs", []byte{}, true)
+	s.methods = map[string]interface{}{
+		"CreateInterface": s.createInterface,
+		"RemoveInterface": s.removeInterface,
+		"GetInterface":    s.getInterface,
+	}
+	if err := s.export(); err != nil {
+		return nil, err
+	}
+	reply, err := conn.RequestName(Service, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("name %s already taken", Service)
+	}
+	return s, nil
+}
+
+// AddInterface adds a managed interface as if it was given on the
+// wpa_supplicant command line
+func (s *Supplicant) AddInterface(ifname string) (*Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.addInterface(ifname, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Interface returns the managed interface with the given name or nil
+func (s *Supplicant) Interface(ifname string) *Interface {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ifaces[ifname]
+}
+
+func (s *Supplicant) updateInterfaces() {
+	paths := make([]dbus.ObjectPath, 0, len(s.ifaces))
+	for _, i := range s.ifaces {
+		paths = append(paths, i.path)
+	}
+	sort.Slice(paths, func(a, b int) bool { return paths[a] < paths[b] })
+	s.setProperty("Interfaces", paths)
+}
+
+func (s *Supplicant) addInterface(ifname, driver, config, bridge string) (*Interface, *dbus.Error) {
+	if _, ok := s.ifaces[ifname]; ok {
+		return nil, NewError(ErrInterfaceExists, "wpa_supplicant already controls this interface.")
+	}
+	path := dbus.ObjectPath(fmt.Sprintf("%s/Interfaces/%d", Path, s.nextID))
+	s.nextID++
+	i := newInterface(s, path, ifname, driver, config, bridge)
+	if err := i.export(); err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+	s.ifaces[ifname] = i
+	s.updateInterfaces()
+	s.emit("InterfaceAdded", path, i.properties())
+	return i, nil
+}
+
+func (s *Supplicant) interfaceByPath(path dbus.ObjectPath) *Interface {
+	for _, i := range s.ifaces {
+		if i.path == path {
+			return i
+		}
+	}
+	return nil
+}
+
+func (s *Supplicant) createInterface(args map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("CreateInterface"); err != nil {
+		return noObjectPath, err
+	}
+	str := func(key string) (string, bool) {
+		v, ok := args[key]
+		if !ok {
+			return "", true
+		}
+		s, ok := v.Value().(string)
+		return s, ok
+	}
+	ifname, ok1 := str("Ifname")
+	driver, ok2 := str("Driver")
+	config, ok3 := str("ConfigFile")
+	bridge, ok4 := str("BridgeIfname")
+	if !ok1 || !ok2 || !ok3 || !ok4 || len(ifname) == 0 {
+		return noObjectPath, NewError(ErrInvalidArgs, "Did not receive correct message arguments.")
+	}
+	i, err := s.addInterface(ifname, driver, config, bridge)
+	if err != nil {
+		return noObjectPath, err
+	}
+	return i.path, nil
+}
+
+func (s *Supplicant) removeInterface(path dbus.ObjectPath) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("RemoveInterface"); err != nil {
+		return err
+	}
+	i := s.interfaceByPath(path)
+	if i == nil {
+		return NewError(ErrInterfaceUnknown, "wpa_supplicant knows nothing about this interface.")
+	}
+	i.remove()
+	delete(s.ifaces, i.ifname)
+	s.updateInterfaces()
+	s.emit("InterfaceRemoved", path)
+	return nil
+}
+
+func (s *Supplicant) getInterface(ifname string) (dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fail("GetInterface"); err != nil {
+		return noObjectPath, err
+	}
+	i, ok := s.ifaces[ifname]
+	if !ok {
+		return noObjectPath, NewError(ErrInterfaceUnknown, "wpa_supplicant knows nothing about this interface.")
+	}
+	return i.path, nil
+}
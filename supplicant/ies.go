@@ -0,0 +1,308 @@
+package supplicant
+
+import (
+	"encoding/binary"
+)
+
+// Element IDs of IEEE 802.11 information elements
+const (
+	ElementSupportedRates         = 1
+	ElementCountry                = 7
+	ElementBSSLoad                = 11
+	ElementHTCapabilities         = 45
+	ElementRSN                    = 48
+	ElementExtendedSupportedRates = 50
+	ElementMobilityDomain         = 54
+	ElementHTOperation            = 61
+	ElementRMEnabledCapabilities  = 70
+	ElementExtendedCapabilities   = 127
+	ElementVHTCapabilities        = 191
+	ElementVHTOperation           = 192
+	ElementVendorSpecific         = 221
+	ElementExtension              = 255
+)
+
+// Element extension IDs, used with ElementExtension
+const (
+	ExtElementHECapabilities  = 35
+	ExtElementHEOperation     = 36
+	ExtElementEHTOperation    = 106
+	ExtElementEHTCapabilities = 108
+)
+
+// Element is a raw information element. For ElementExtension the
+// extension ID is split off the data.
+type Element struct {
+	ID    byte
+	ExtID byte
+	Data  []byte
+}
+
+// ParseElements splits a byte array into information elements. A
+// truncated last element is dropped.
+func ParseElements(data []byte) []Element {
+	var elems []Element
+	for len(data) >= 2 {
+		id, length := data[0], int(data[1])
+		if len(data) < 2+length {
+			break
+		}
+		e := Element{ID: id, Data: data[2 : 2+length]}
+		if id == ElementExtension {
+			if length == 0 {
+				data = data[2+length:]
+				continue
+			}
+			e.ExtID, e.Data = e.Data[0], e.Data[1:]
+		}
+		elems = append(elems, e)
+		data = data[2+length:]
+	}
+	return elems
+}
+
+// BSSLoad is the content of the BSS Load element
+type BSSLoad struct {
+	StationCount uint16
+	// ChannelUtilization is the share of time the medium was busy,
+	// scaled to 0..255
+	ChannelUtilization uint8
+	// AdmissionCapacity is the remaining medium time in units of 32 µs
+	// per second
+	AdmissionCapacity uint16
+}
+
+// MobilityDomain is the content of the Mobility Domain element of
+// 802.11r fast BSS transition
+type MobilityDomain struct {
+	ID                      uint16
+	OverDS                  bool
+	ResourceRequestProtocol bool
+}
+
+// RMCapabilities are the radio measurement capabilities of 802.11k
+type RMCapabilities struct {
+	LinkMeasurement bool
+	NeighborReport  bool
+	BeaconPassive   bool
+	BeaconActive    bool
+	BeaconTable     bool
+}
+
+// ElementInfo summarizes the information elements of a BSS
+type ElementInfo struct {
+	// Generation is the newest supported PHY: "be", "ax", "ac" or "n".
+	// ParseElementInfo leaves it empty for legacy BSSs, BSSInfo.Elements
+	// sets "a", "b" or "g" then.
+	Generation string
+	// ChannelWidth is the operating channel width in MHz
+	ChannelWidth int
+	// SpatialStreams is the number of receive spatial streams, 0 if
+	// unknown
+	SpatialStreams int
+	// Country is the country code of the Country element, e.g. "DE"
+	Country        string
+	BSSLoad        *BSSLoad
+	MobilityDomain *MobilityDomain
+	RMCapabilities *RMCapabilities
+	// BSSTransition reports support of 802.11v BSS transition management
+	BSSTransition bool
+	// MaxRate is the highest basic or extended rate in Mbit/s
+	MaxRate float64
+}
+
+// mcsMapStreams counts the spatial streams of a VHT or HE MCS map, in
+// which each stream has two bits and 3 means not supported
+func mcsMapStreams(m uint16) int {
+	n := 0
+	for ss := 0; ss < 8; ss++ {
+		if (m>>(2*ss))&3 != 3 {
+			n = ss + 1
+		}
+	}
+	return n
+}
+
+// centerWidth derives the width of a VHT or HE 6 GHz operation from the
+// channel center frequency segments
+func centerWidth(width80 int, seg0, seg1 byte) int {
+	if seg1 == 0 {
+		return width80
+	}
+	diff := int(seg1) - int(seg0)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff == 8 || diff > 16 {
+		return 160
+	}
+	return width80
+}
+
+// ParseElementInfo decodes the information elements of a BSS
+func ParseElementInfo(ies []byte) *ElementInfo {
+	info := &ElementInfo{ChannelWidth: 20}
+	width := func(w int) {
+		if w > info.ChannelWidth {
+			info.ChannelWidth = w
+		}
+	}
+	streams := func(n int) {
+		if n > info.SpatialStreams {
+			info.SpatialStreams = n
+		}
+	}
+	generation := func(g string) {
+		order := map[string]int{"": 0, "n": 1, "ac": 2, "ax": 3, "be": 4}
+		if order[g] > order[info.Generation] {
+			info.Generation = g
+		}
+	}
+	for _, e := range ParseElements(ies) {
+		d := e.Data
+		switch e.ID {
+		case ElementSupportedRates, ElementExtendedSupportedRates:
+			for _, r := range d {
+				if rate := float64(r&0x7f) / 2; rate > info.MaxRate {
+					info.MaxRate = rate
+				}
+			}
+		case ElementCountry:
+			if len(d) >= 2 {
+				info.Country = string(d[:2])
+			}
+		case ElementBSSLoad:
+			if len(d) >= 5 {
+				info.BSSLoad = &BSSLoad{
+					StationCount:       binary.LittleEndian.Uint16(d[0:]),
+					ChannelUtilization: d[2],
+					AdmissionCapacity:  binary.LittleEndian.Uint16(d[3:]),
+				}
+			}
+		case ElementMobilityDomain:
+			if len(d) >= 3 {
+				info.MobilityDomain = &MobilityDomain{
+					ID:                      binary.LittleEndian.Uint16(d[0:]),
+					OverDS:                  d[2]&0x01 != 0,
+					ResourceRequestProtocol: d[2]&0x02 != 0,
+				}
+			}
+		case ElementRMEnabledCapabilities:
+			if len(d) >= 1 {
+				info.RMCapabilities = &RMCapabilities{
+					LinkMeasurement: d[0]&0x01 != 0,
+					NeighborReport:  d[0]&0x02 != 0,
+					BeaconPassive:   d[0]&0x10 != 0,
+					BeaconActive:    d[0]&0x20 != 0,
+					BeaconTable:     d[0]&0x40 != 0,
+				}
+			}
+		case ElementExtendedCapabilities:
+			/* Bit 19 is BSS Transition */
+			info.BSSTransition = len(d) >= 3 && d[2]&0x08 != 0
+		case ElementHTCapabilities:
+			generation("n")
+			if len(d) >= 7 {
+				/* Rx MCS bitmask, one byte per spatial stream */
+				n := 0
+				for ss := 0; ss < 4; ss++ {
+					if d[3+ss] != 0 {
+						n = ss + 1
+					}
+				}
+				streams(n)
+			}
+		case ElementHTOperation:
+			generation("n")
+			/* Secondary channel offset and STA channel width */
+			if len(d) >= 2 && d[1]&0x03 != 0 && d[1]&0x04 != 0 {
+				width(40)
+			}
+		case ElementVHTCapabilities:
+			generation("ac")
+			if len(d) >= 6 {
+				streams(mcsMapStreams(binary.LittleEndian.Uint16(d[4:])))
+			}
+		case ElementVHTOperation:
+			generation("ac")
+			if len(d) >= 3 {
+				switch d[0] {
+				case 1:
+					width(centerWidth(80, d[1], d[2]))
+				case 2, 3:
+					width(160)
+				}
+			}
+		case ElementExtension:
+			switch e.ExtID {
+			case ExtElementHECapabilities:
+				generation("ax")
+				/* MAC (6) and PHY (11) capabilities, then the Rx MCS map
+				 * for channels up to 80 MHz */
+				if len(d) >= 19 {
+					streams(mcsMapStreams(binary.LittleEndian.Uint16(d[17:])))
+				}
+			case ExtElementHEOperation:
+				generation("ax")
+				if len(d) < 6 {
+					break
+				}
+				params := uint32(d[0]) | uint32(d[1])<<8 | uint32(d[2])<<16
+				off := 6
+				if params&(1<<14) != 0 {
+					/* VHT Operation Information */
+					if len(d) >= off+3 && d[off] == 1 {
+						width(centerWidth(80, d[off+1], d[off+2]))
+					}
+					off += 3
+				}
+				if params&(1<<15) != 0 {
+					off++
+				}
+				if params&(1<<17) != 0 && len(d) >= off+5 {
+					/* 6 GHz Operation Information */
+					switch d[off+1] & 0x03 {
+					case 1:
+						width(40)
+					case 2:
+						width(80)
+					case 3:
+						width(160)
+					}
+				}
+			case ExtElementEHTCapabilities:
+				generation("be")
+			case ExtElementEHTOperation:
+				generation("be")
+				/* Parameters (1), basic EHT-MCS and NSS set (4), then the
+				 * optional operation information */
+				if len(d) >= 8 && d[0]&0x01 != 0 {
+					width([]int{20, 40, 80, 160, 320, 20, 20, 20}[d[5]&0x07])
+				}
+			}
+		}
+	}
+	return info
+}
+
+// Elements decodes the information elements of the BSS. The generation
+// of legacy BSSs is derived from the frequency and the rates.
+func (b *BSSInfo) Elements() *ElementInfo {
+	info := ParseElementInfo(b.IEs)
+	for _, r := range b.Rates {
+		if rate := float64(r) / 1e6; rate > info.MaxRate {
+			info.MaxRate = rate
+		}
+	}
+	if len(info.Generation) == 0 && info.MaxRate > 0 {
+		switch {
+		case b.Frequency >= 5000:
+			info.Generation = "a"
+		case info.MaxRate > 11:
+			info.Generation = "g"
+		default:
+			info.Generation = "b"
+		}
+	}
+	return info
+}
@@ -0,0 +1,205 @@
+package supplicant
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Information elements as sent in the beacons of access points, one
+// element per string
+var (
+	ieSSID       = "00 06 4f6666696365"
+	ieRates24    = "01 08 82848b960c121824"
+	ieRates5     = "01 08 8c129824b048606c"
+	ieDSParams   = "03 01 06"
+	ieTIM        = "05 04 00010000"
+	ieCountryDE  = "07 06 444520010d14"
+	ieCountryUS  = "07 06 555320240417"
+	ieBSSLoad    = "0b 05 03004a0000"
+	ieERP        = "2a 01 00"
+	ieHTCap2SS   = "2d 1a ef11 1b ffff0000000000000000000000000000 0000 00000000 00"
+	ieHTCap3SS   = "2d 1a ef09 1b ffffff00000000000000000000000000 0000 00000000 00"
+	ieRSNPSK     = "30 14 0100000fac040100000fac040100000fac020c00"
+	ieRSNFT      = "30 18 0100000fac040100000fac040200000fac02000fac040c00"
+	ieExtRates   = "32 04 3048606c"
+	ieHTOp20     = "3d 16 06000000000000000000000000000000000000000000"
+	ieHTOp40     = "3d 16 24050000000000000000000000000000000000000000"
+	ieMobility   = "36 03 a1b201"
+	ieRMCaps     = "46 05 7300000000"
+	ieExtCaps    = "7f 08 0400080000000040"
+	ieVHTCap3SS  = "bf 0c b2799133eaff0c03eaff0c03"
+	ieVHTOp80    = "c0 05 012a00fcff"
+	ieVHTOp160   = "c0 05 012a32fcff"
+	ieHECap2SS   = "ff 16 23 0178c81a4000 222002c00d419508008c00 faff faff"
+	ieHEOp80     = "ff 0a 24 044000 12 fcff 012a00"
+	ieHEOp6G160  = "ff 0c 24 040002 05 fcff 25032f1f00"
+	ieEHTCap     = "ff 03 6c 0000"
+	ieEHTOp320   = "ff 09 6a 01 11111111 041f3f"
+	ieWMM        = "dd 18 0050f2020101800003a4000027a4000042435e0062322f00"
+	ieVendorMTK  = "dd 07 000ce708000000"
+	ieHTCapShort = "2d 03 ef111b"
+)
+
+func unhex(t *testing.T, elements ...string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(strings.ReplaceAll(strings.Join(elements, ""), " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseElements(t *testing.T) {
+	tests := []struct {
+		name string
+		ies  []byte
+		want []Element
+	}{
+		{"empty", nil, nil},
+		{"one byte", []byte{0x00}, nil},
+		{"ssid", unhex(t, ieSSID), []Element{{ID: 0, Data: []byte("Office")}}},
+		{"extension", unhex(t, ieEHTCap), []Element{{ID: ElementExtension, ExtID: ExtElementEHTCapabilities, Data: []byte{0, 0}}}},
+		{"empty extension", unhex(t, "ff 00", ieDSParams), []Element{{ID: 3, Data: []byte{6}}}},
+		{"truncated", unhex(t, ieDSParams, "2d 1a ef11"), []Element{{ID: 3, Data: []byte{6}}}},
+		{"zero length", unhex(t, "00 00", ieDSParams), []Element{{ID: 0, Data: []byte{}}, {ID: 3, Data: []byte{6}}}},
+	}
+	for _, tt := range tests {
+		if got := ParseElements(tt.ies); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseElements = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseElementInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		ies  []byte
+		want ElementInfo
+	}{
+		{
+			name: "no elements",
+			want: ElementInfo{ChannelWidth: 20},
+		},
+		{
+			name: "802.11g",
+			ies:  unhex(t, ieSSID, ieRates24, ieDSParams, ieTIM, ieERP, ieExtRates),
+			want: ElementInfo{ChannelWidth: 20, MaxRate: 54},
+		},
+		{
+			name: "802.11n 2.4 GHz",
+			ies:  unhex(t, ieSSID, ieRates24, ieDSParams, ieTIM, ieCountryDE, ieBSSLoad, ieERP, ieHTCap2SS, ieRSNPSK, ieExtRates, ieHTOp20, ieExtCaps, ieWMM),
+			want: ElementInfo{
+				Generation:     "n",
+				ChannelWidth:   20,
+				SpatialStreams: 2,
+				Country:        "DE",
+				BSSLoad:        &BSSLoad{StationCount: 3, ChannelUtilization: 74},
+				BSSTransition:  true,
+				MaxRate:        54,
+			},
+		},
+		{
+			name: "802.11n 40 MHz",
+			ies:  unhex(t, ieSSID, ieRates5, ieHTCap3SS, ieHTOp40),
+			want: ElementInfo{Generation: "n", ChannelWidth: 40, SpatialStreams: 3, MaxRate: 54},
+		},
+		{
+			name: "802.11ac 80 MHz with 802.11k/r",
+			ies:  unhex(t, ieSSID, ieRates5, ieTIM, ieCountryUS, ieRSNFT, ieMobility, ieRMCaps, ieHTCap3SS, ieHTOp40, ieExtCaps, ieVHTCap3SS, ieVHTOp80, ieWMM),
+			want: ElementInfo{
+				Generation:     "ac",
+				ChannelWidth:   80,
+				SpatialStreams: 3,
+				Country:        "US",
+				MobilityDomain: &MobilityDomain{ID: 0xb2a1, OverDS: true},
+				RMCapabilities: &RMCapabilities{LinkMeasurement: true, NeighborReport: true, BeaconPassive: true, BeaconActive: true, BeaconTable: true},
+				BSSTransition:  true,
+				MaxRate:        54,
+			},
+		},
+		{
+			name: "802.11ac 160 MHz",
+			ies:  unhex(t, ieSSID, ieRates5, ieHTCap3SS, ieHTOp40, ieVHTCap3SS, ieVHTOp160),
+			want: ElementInfo{Generation: "ac", ChannelWidth: 160, SpatialStreams: 3, MaxRate: 54},
+		},
+		{
+			name: "802.11ax 5 GHz",
+			ies:  unhex(t, ieSSID, ieRates5, ieRSNPSK, ieHTCap2SS, ieHTOp40, ieVHTCap3SS, ieHECap2SS, ieHEOp80, ieWMM, ieVendorMTK),
+			want: ElementInfo{Generation: "ax", ChannelWidth: 80, SpatialStreams: 3, MaxRate: 54},
+		},
+		{
+			name: "802.11ax 6 GHz",
+			ies:  unhex(t, ieSSID, ieRates5, ieRSNPSK, ieHECap2SS, ieHEOp6G160),
+			want: ElementInfo{Generation: "ax", ChannelWidth: 160, SpatialStreams: 2, MaxRate: 54},
+		},
+		{
+			name: "802.11be 320 MHz",
+			ies:  unhex(t, ieSSID, ieRates5, ieHECap2SS, ieHEOp6G160, ieEHTCap, ieEHTOp320),
+			want: ElementInfo{Generation: "be", ChannelWidth: 320, SpatialStreams: 2, MaxRate: 54},
+		},
+		{
+			name: "truncated last element",
+			ies:  unhex(t, ieSSID, ieRates24, "2d 1a ef111bffff"),
+			want: ElementInfo{ChannelWidth: 20, MaxRate: 18},
+		},
+		{
+			name: "length beyond the data",
+			ies:  unhex(t, ieSSID, "01 ff 82848b96"),
+			want: ElementInfo{ChannelWidth: 20},
+		},
+		{
+			name: "short elements",
+			ies: unhex(t,
+				ieHTCapShort,
+				"07 01 44",        // country without code
+				"0b 02 0300",      // BSS load without utilization
+				"36 02 a1b2",      // mobility domain without capabilities
+				"46 00",           // RM capabilities without bits
+				"7f 01 04",        // extended capabilities without bit 19
+				"3d 01 24",        // HT operation without channel width
+				"bf 04 b2799133",  // VHT capabilities without MCS map
+				"c0 02 012a",      // VHT operation without segment 1
+				"ff 04 24 044000", // HE operation without BSS color and MCS set
+				"ff 02 6a 01",     // EHT operation without information
+			),
+			want: ElementInfo{Generation: "be", ChannelWidth: 20},
+		},
+		{
+			name: "HE operation with truncated VHT information",
+			ies:  unhex(t, "ff 08 24 044000 12 fcff 01"),
+			want: ElementInfo{Generation: "ax", ChannelWidth: 20},
+		},
+		{
+			name: "EHT operation without information present",
+			ies:  unhex(t, "ff 09 6a 00 11111111 041f3f"),
+			want: ElementInfo{Generation: "be", ChannelWidth: 20},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseElementInfo(tt.ies); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: ParseElementInfo = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestBSSInfoElements(t *testing.T) {
+	tests := []struct {
+		name string
+		info BSSInfo
+		gen  string
+		rate float64
+	}{
+		{"802.11b", BSSInfo{Frequency: 2412, Rates: []uint32{11000000, 5500000, 2000000, 1000000}}, "b", 11},
+		{"802.11g", BSSInfo{Frequency: 2437, Rates: []uint32{54000000, 11000000}}, "g", 54},
+		{"802.11a", BSSInfo{Frequency: 5180, Rates: []uint32{54000000, 6000000}}, "a", 54},
+		{"802.11n", BSSInfo{Frequency: 2412, Rates: []uint32{54000000}, IEs: unhex(t, ieHTCap2SS)}, "n", 54},
+		{"nothing known", BSSInfo{Frequency: 2412}, "", 0},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Elements(); got.Generation != tt.gen || got.MaxRate != tt.rate {
+			t.Errorf("%s: generation %q, max rate %v, want %q, %v", tt.name, got.Generation, got.MaxRate, tt.gen, tt.rate)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package supplicant
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Interface represents a network interface managed by the supplicant
+type Interface struct {
+	object
+}
+
+// Path returns the D-Bus object path of the interface
+func (i *Interface) Path() dbus.ObjectPath {
+	return i.path
+}
+
+// Properties fetches all interface properties with a single call
+func (i *Interface) Properties(ctx context.Context) (map[string]dbus.Variant, error) {
+	return i.getAll(ctx)
+}
+
+// InterfaceInfo holds the commonly used properties of an interface
+type InterfaceInfo struct {
+	Path             dbus.ObjectPath
+	Ifname           string
+	Driver           string
+	State            string
+	Scanning         bool
+	CurrentAuthMode  string
+	CurrentBSS       dbus.ObjectPath
+	CurrentNetwork   dbus.ObjectPath
+	DisconnectReason int32
+}
+
+// Info fetches the properties of the interface with a single call
+func (i *Interface) Info(ctx context.Context) (*InterfaceInfo, error) {
+	props, err := i.getAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := &InterfaceInfo{Path: i.path}
+	info.Ifname, _ = props["Ifname"].Value().(string)
+	info.Driver, _ = props["Driver"].Value().(string)
+	info.State, _ = props["State"].Value().(string)
+	info.Scanning, _ = props["Scanning"].Value().(bool)
+	info.CurrentAuthMode, _ = props["CurrentAuthMode"].Value().(string)
+	info.CurrentBSS, _ = props["CurrentBSS"].Value().(dbus.ObjectPath)
+	info.CurrentNetwork, _ = props["CurrentNetwork"].Value().(dbus.ObjectPath)
+	info.DisconnectReason, _ = props["DisconnectReason"].Value().(int32)
+	return info, nil
+}
+
+// Property returns the raw value of the named interface property
+func (i *Interface) Property(ctx context.Context, name string) (interface{}, error) {
+	v, err := i.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return v.Value(), nil
+}
+
+// SetProperty changes the named interface property
+func (i *Interface) SetProperty(ctx context.Context, name string, value interface{}) error {
+	return i.set(ctx, name, value)
+}
+
+func (i *Interface) stringProperty(ctx context.Context, name string) (string, error) {
+	v, err := i.get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	s, _ := v.Value().(string)
+	return s, nil
+}
+
+// Ifname returns the name of the network interface
+func (i *Interface) Ifname(ctx context.Context) (string, error) {
+	return i.stringProperty(ctx, "Ifname")
+}
+
+// State returns the connection state, e.g. "completed" or "disconnected"
+func (i *Interface) State(ctx context.Context) (string, error) {
+	return i.stringProperty(ctx, "State")
+}
+
+// ConfigFile returns the path of the configuration file the interface was
+// created with
+func (i *Interface) ConfigFile(ctx context.Context) (string, error) {
+	return i.stringProperty(ctx, "ConfigFile")
+}
+
+// CurrentAuthMode returns the authentication mode of the current connection
+func (i *Interface) CurrentAuthMode(ctx context.Context) (string, error) {
+	return i.stringProperty(ctx, "CurrentAuthMode")
+}
+
+// Scanning reports whether a scan is in progress
+func (i *Interface) Scanning(ctx context.Context) (bool, error) {
+	v, err := i.get(ctx, "Scanning")
+	if err != nil {
+		return false, err
+	}
+	b, _ := v.Value().(bool)
+	return b, nil
+}
+
+func (i *Interface) pathList(ctx context.Context, name string) ([]dbus.ObjectPath, error) {
+	v, err := i.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	paths, _ := v.Value().([]dbus.ObjectPath)
+	return paths, nil
+}
+
+// BSSs returns the BSSs found by the last scans
+func (i *Interface) BSSs(ctx context.Context) ([]*BSS, error) {
+	paths, err := i.pathList(ctx, "BSSs")
+	if err != nil {
+		return nil, err
+	}
+	bsss := make([]*BSS, 0, len(paths))
+	for _, p := range paths {
+		bsss = append(bsss, i.s.BSS(p))
+	}
+	return bsss, nil
+}
+
+// CurrentBSS returns the BSS the interface is associated with or nil
+func (i *Interface) CurrentBSS(ctx context.Context) (*BSS, error) {
+	v, err := i.get(ctx, "CurrentBSS")
+	if err != nil {
+		return nil, err
+	}
+	if p, _ := v.Value().(dbus.ObjectPath); p.IsValid() && p != noObjectPath {
+		return i.s.BSS(p), nil
+	}
+	return nil, nil
+}
+
+// Networks returns the configured networks
+func (i *Interface) Networks(ctx context.Context) ([]*Network, error) {
+	paths, err := i.pathList(ctx, "Networks")
+	if err != nil {
+		return nil, err
+	}
+	nets := make([]*Network, 0, len(paths))
+	for _, p := range paths {
+		nets = append(nets, i.s.Network(p))
+	}
+	return nets, nil
+}
+
+// ScanChannel is a channel to scan, given by its center frequency and
+// width in MHz
+type ScanChannel struct {
+	Frequency uint32
+	Width     uint32
+}
+
+// ScanArgs are the arguments of Scan. Type is "active" or "passive", an
+// empty Type means "active". A nil AllowRoam leaves the roaming decision
+// to wpa_supplicant's default, which is to allow it.
+type ScanArgs struct {
+	Type      string
+	AllowRoam *bool
+	// SSIDs are probed for in an active scan, which finds hidden
+	// networks. An empty SSID requests a wildcard probe.
+	SSIDs [][]byte
+	// IEs are added to the probe requests
+	IEs [][]byte
+	// Channels limits the scan, all channels are scanned if empty
+	Channels []ScanChannel
+}
+
+func (a ScanArgs) dict() map[string]interface{} {
+	d := map[string]interface{}{"Type": a.Type}
+	if len(a.Type) == 0 {
+		d["Type"] = "active"
+	}
+	if a.AllowRoam != nil {
+		d["AllowRoam"] = *a.AllowRoam
+	}
+	if len(a.SSIDs) > 0 {
+		d["SSIDs"] = a.SSIDs
+	}
+	if len(a.IEs) > 0 {
+		d["IEs"] = a.IEs
+	}
+	if len(a.Channels) > 0 {
+		d["Channels"] = a.Channels
+	}
+	return d
+}
+
+// Scan triggers a scan. It returns as soon as the scan is started, the
+// end is signaled by ScanDone.
+func (i *Interface) Scan(ctx context.Context, args ScanArgs) error {
+	return i.call(ctx, "Scan", args.dict()).Err
+}
+
+// AbortScan stops a running scan, which then ends with a failed ScanDone
+func (i *Interface) AbortScan(ctx context.Context) error {
+	return i.call(ctx, "AbortScan").Err
+}
+
+// AutoScan sets the background scanning of a disconnected interface, e.g.
+// "exponential:3:300" or "periodic:30". An empty arg disables it.
+func (i *Interface) AutoScan(ctx context.Context, arg string) error {
+	return i.call(ctx, "AutoScan", arg).Err
+}
+
+// Reassociate forces a reassociation
+func (i *Interface) Reassociate(ctx context.Context) error {
+	return i.call(ctx, "Reassociate").Err
+}
+
+// Reattach forces a reassociation back to the same BSS
+func (i *Interface) Reattach(ctx context.Context) error {
+	return i.call(ctx, "Reattach").Err
+}
+
+// Reconnect is like Reassociate, but only takes effect if disconnected
+func (i *Interface) Reconnect(ctx context.Context) error {
+	return i.call(ctx, "Reconnect").Err
+}
+
+// Disconnect disconnects and waits for a Reassociate or Reconnect
+func (i *Interface) Disconnect(ctx context.Context) error {
+	return i.call(ctx, "Disconnect").Err
+}
+
+// SignalPoll returns the signal parameters of the current connection
+func (i *Interface) SignalPoll(ctx context.Context) (info map[string]dbus.Variant, err error) {
+	err = i.call(ctx, "SignalPoll").Store(&info)
+	return
+}
+
+// FlushBSS removes BSS entries older than age seconds from the cache.
+// An age of 0 removes all entries.
+func (i *Interface) FlushBSS(ctx context.Context, age uint32) error {
+	return i.call(ctx, "FlushBSS", age).Err
+}
+
+// SaveConfig writes the current configuration to the configuration file
+// of the interface. wpa_supplicant refuses this unless the file sets
+// update_config=1.
+func (i *Interface) SaveConfig(ctx context.Context) error {
+	return i.call(ctx, "SaveConfig").Err
+}
+
+// AddNetwork adds a network block built from the given properties
+func (i *Interface) AddNetwork(ctx context.Context, props map[string]interface{}) (*Network, error) {
+	var p dbus.ObjectPath
+	if err := i.call(ctx, "AddNetwork", props).Store(&p); err != nil {
+		return nil, err
+	}
+	return i.s.Network(p), nil
+}
+
+// RemoveNetwork removes the given network
+func (i *Interface) RemoveNetwork(ctx context.Context, n *Network) error {
+	return i.call(ctx, "RemoveNetwork", n.path).Err
+}
+
+// RemoveAllNetworks removes all configured networks
+func (i *Interface) RemoveAllNetworks(ctx context.Context) error {
+	return i.call(ctx, "RemoveAllNetworks").Err
+}
+
+// SelectNetwork selects the given network and disables the others
+func (i *Interface) SelectNetwork(ctx context.Context, n *Network) error {
+	return i.call(ctx, "SelectNetwork", n.path).Err
+}
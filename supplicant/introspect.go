@@ -0,0 +1,136 @@
+package supplicant
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const introspectableIface = "org.freedesktop.DBus.Introspectable"
+
+// PropertyDesc describes a property as announced by the introspection
+// data of an object
+type PropertyDesc struct {
+	Name      string
+	Signature dbus.Signature
+	Writable  bool
+}
+
+// describe returns the properties of the object's interface sorted by
+// name
+func (o object) describe(ctx context.Context) ([]PropertyDesc, error) {
+	var data string
+	if err := o.callMethod(ctx, introspectableIface+".Introspect").Store(&data); err != nil {
+		return nil, err
+	}
+	var node introspect.Node
+	if err := xml.Unmarshal([]byte(data), &node); err != nil {
+		return nil, fmt.Errorf("invalid introspection data of %s: %v", o.path, err)
+	}
+	var descs []PropertyDesc
+	for _, iface := range node.Interfaces {
+		if iface.Name != o.iface {
+			continue
+		}
+		for _, p := range iface.Properties {
+			sig, err := dbus.ParseSignature(p.Type)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signature of property %s: %v", p.Name, err)
+			}
+			descs = append(descs, PropertyDesc{Name: p.Name, Signature: sig, Writable: strings.Contains(p.Access, "write")})
+		}
+	}
+	sort.Slice(descs, func(a, b int) bool { return descs[a].Name < descs[b].Name })
+	return descs, nil
+}
+
+// DescribeProperties returns the properties of the interface object
+// sorted by name
+func (i *Interface) DescribeProperties(ctx context.Context) ([]PropertyDesc, error) {
+	return i.describe(ctx)
+}
+
+// ParseValue converts text to a value of the D-Bus type sig. Besides the
+// basic types it accepts comma-separated lists for "as", hex strings for
+// "ay" and comma-separated key=hex pairs for "a{say}".
+func ParseValue(sig dbus.Signature, text string) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch sig.String() {
+	case "s":
+		v = text
+	case "o":
+		if !dbus.ObjectPath(text).IsValid() {
+			err = fmt.Errorf("invalid object path")
+		}
+		v = dbus.ObjectPath(text)
+	case "b":
+		v, err = strconv.ParseBool(text)
+	case "y":
+		var n uint64
+		n, err = strconv.ParseUint(text, 0, 8)
+		v = byte(n)
+	case "n":
+		var n int64
+		n, err = strconv.ParseInt(text, 0, 16)
+		v = int16(n)
+	case "q":
+		var n uint64
+		n, err = strconv.ParseUint(text, 0, 16)
+		v = uint16(n)
+	case "i":
+		var n int64
+		n, err = strconv.ParseInt(text, 0, 32)
+		v = int32(n)
+	case "u":
+		var n uint64
+		n, err = strconv.ParseUint(text, 0, 32)
+		v = uint32(n)
+	case "x":
+		v, err = strconv.ParseInt(text, 0, 64)
+	case "t":
+		v, err = strconv.ParseUint(text, 0, 64)
+	case "d":
+		v, err = strconv.ParseFloat(text, 64)
+	case "as":
+		list := []string{}
+		if len(text) > 0 {
+			list = strings.Split(text, ",")
+		}
+		v = list
+	case "ay":
+		v, err = hex.DecodeString(strings.ReplaceAll(text, ":", ""))
+	case "a{say}":
+		m := map[string][]byte{}
+		for _, pair := range strings.Split(text, ",") {
+			if len(pair) == 0 {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				err = fmt.Errorf("expected key=hex")
+				break
+			}
+			if m[kv[0]], err = hex.DecodeString(strings.ReplaceAll(kv[1], ":", "")); err != nil {
+				break
+			}
+		}
+		v = m
+	default:
+		return nil, fmt.Errorf("values of type %s are not supported", sig)
+	}
+	if ne, ok := err.(*strconv.NumError); ok {
+		err = ne.Err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %q to type %s: %v", text, sig, err)
+	}
+	return v, nil
+}
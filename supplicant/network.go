@@ -0,0 +1,109 @@
+package supplicant
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Network represents a configured network block
+type Network struct {
+	object
+}
+
+// Network returns the network object for the given path without checking
+// its existence
+func (s *Supplicant) Network(path dbus.ObjectPath) *Network {
+	return &Network{object{s: s, path: path, iface: NetworkIface}}
+}
+
+// Path returns the D-Bus object path of the network
+func (n *Network) Path() dbus.ObjectPath {
+	return n.path
+}
+
+// ID returns the network id of wpa_supplicant, i.e. the last element of
+// the object path, or -1 if the path has no such element
+func (n *Network) ID() int {
+	return networkID(n.path)
+}
+
+func networkID(p dbus.ObjectPath) int {
+	id, err := strconv.Atoi(path.Base(string(p)))
+	if err != nil || id < 0 {
+		return -1
+	}
+	return id
+}
+
+// Properties returns the network block fields. The values are strings in
+// wpa_supplicant.conf syntax, i.e. string fields are enclosed in quotes.
+func (n *Network) Properties(ctx context.Context) (map[string]dbus.Variant, error) {
+	v, err := n.get(ctx, "Properties")
+	if err != nil {
+		return nil, err
+	}
+	props, _ := v.Value().(map[string]dbus.Variant)
+	return props, nil
+}
+
+// SetProperties changes the given network block fields. The values are
+// converted like those of AddNetwork, other fields keep their values.
+func (n *Network) SetProperties(ctx context.Context, props map[string]interface{}) error {
+	return n.set(ctx, "Properties", props)
+}
+
+// NetworkInfo holds the properties of a network
+type NetworkInfo struct {
+	Path       dbus.ObjectPath
+	ID         int
+	Properties map[string]dbus.Variant
+	Enabled    bool
+}
+
+// Info fetches the properties of the network with a single call
+func (n *Network) Info(ctx context.Context) (*NetworkInfo, error) {
+	props, err := n.getAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := &NetworkInfo{Path: n.path, ID: n.ID()}
+	info.Properties, _ = props["Properties"].Value().(map[string]dbus.Variant)
+	info.Enabled, _ = props["Enabled"].Value().(bool)
+	return info, nil
+}
+
+// NetworkInfos fetches the properties of all networks of the interface in
+// the order of the Networks property
+func (i *Interface) NetworkInfos(ctx context.Context) ([]*NetworkInfo, error) {
+	nets, err := i.Networks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*NetworkInfo, len(nets))
+	err = i.s.parallel(ctx, len(nets), func(ctx context.Context, idx int) (err error) {
+		infos[idx], err = nets[idx].Info(ctx)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// Enabled reports whether the network is enabled
+func (n *Network) Enabled(ctx context.Context) (bool, error) {
+	v, err := n.get(ctx, "Enabled")
+	if err != nil {
+		return false, err
+	}
+	b, _ := v.Value().(bool)
+	return b, nil
+}
+
+// SetEnabled enables or disables the network
+func (n *Network) SetEnabled(ctx context.Context, enabled bool) error {
+	return n.set(ctx, "Enabled", enabled)
+}
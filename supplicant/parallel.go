@@ -0,0 +1,51 @@
+package supplicant
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultConcurrency = 8
+
+// parallel calls fn for the indices 0 to n-1 with at most s.Concurrency
+// calls running at the same time. It returns the first error and cancels
+// the context of the remaining calls.
+func (s *Supplicant) parallel(ctx context.Context, n int, fn func(ctx context.Context, idx int) error) error {
+	limit := s.Concurrency
+	if limit <= 0 {
+		limit = defaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, limit)
+	for idx := 0; idx < n; idx++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, idx); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(idx)
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
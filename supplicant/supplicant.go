@@ -0,0 +1,222 @@
+// Package supplicant is a client for the D-Bus API of wpa_supplicant
+// (fi.w1.wpa_supplicant1).
+package supplicant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	Service         = "fi.w1.wpa_supplicant1"
+	Path            = dbus.ObjectPath("/fi/w1/wpa_supplicant1")
+	RootIface       = Service
+	InterfaceIface  = RootIface + ".Interface"
+	BSSIface        = RootIface + ".BSS"
+	NetworkIface    = RootIface + ".Network"
+	propertiesIface = "org.freedesktop.DBus.Properties"
+	noObjectPath    = dbus.ObjectPath("/")
+)
+
+// object is the common part of all remote supplicant objects
+type object struct {
+	s     *Supplicant
+	path  dbus.ObjectPath
+	iface string
+}
+
+func (o object) bus() dbus.BusObject {
+	return o.s.conn.Object(Service, o.path)
+}
+
+// callMethod invokes a D-Bus method on the object, limited by the call
+// timeout of the supplicant. Error replies are returned as *Error, running
+// out of the call timeout as *TimeoutError.
+func (o object) callMethod(ctx context.Context, method string, args ...interface{}) *dbus.Call {
+	callCtx := ctx
+	if o.s.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, o.s.Timeout)
+		defer cancel()
+	}
+	c := o.bus().CallWithContext(callCtx, method, 0, args...)
+	c.Err = wrapError(c.Err)
+	/* Only the own timeout is reported, not the end of the caller's context */
+	if o.s.Timeout > 0 && errors.Is(c.Err, context.DeadlineExceeded) && ctx.Err() == nil {
+		c.Err = &TimeoutError{Op: "waiting for the reply to " + callName(method, args), After: o.s.Timeout, Err: c.Err}
+	}
+	return c
+}
+
+// callName describes a method call in error messages, e.g.
+// ´Interface.Scan´ or ´Get State´
+func callName(method string, args []interface{}) string {
+	if member := strings.TrimPrefix(method, propertiesIface+"."); member != method {
+		if len(args) >= 2 {
+			if prop, ok := args[1].(string); ok {
+				return member + " " + prop
+			}
+		}
+		return member
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(method, RootIface), ".")
+}
+
+func (o object) call(ctx context.Context, method string, args ...interface{}) *dbus.Call {
+	return o.callMethod(ctx, o.iface+"."+method, args...)
+}
+
+func (o object) get(ctx context.Context, prop string) (v dbus.Variant, err error) {
+	err = o.callMethod(ctx, propertiesIface+".Get", o.iface, prop).Store(&v)
+	return
+}
+
+func (o object) getAll(ctx context.Context) (props map[string]dbus.Variant, err error) {
+	err = o.callMethod(ctx, propertiesIface+".GetAll", o.iface).Store(&props)
+	return
+}
+
+func (o object) set(ctx context.Context, prop string, value interface{}) error {
+	return o.callMethod(ctx, propertiesIface+".Set", o.iface, prop, dbus.MakeVariant(value)).Err
+}
+
+// Supplicant represents the root object of wpa_supplicant
+type Supplicant struct {
+	object
+	conn *dbus.Conn
+	// Timeout limits the duration of each D-Bus call, 0 means no limit
+	Timeout time.Duration
+	// Concurrency limits the number of parallel calls made by methods
+	// which fetch many objects, e.g. Interface.BSSInfos. 0 means 8.
+	Concurrency int
+}
+
+// New returns a client for the supplicant service reachable through conn
+func New(conn *dbus.Conn) *Supplicant {
+	s := &Supplicant{conn: conn}
+	s.object = object{s: s, path: Path, iface: RootIface}
+	return s
+}
+
+// Conn returns the underlying D-Bus connection
+func (s *Supplicant) Conn() *dbus.Conn {
+	return s.conn
+}
+
+// Interface returns the interface object for the given path without
+// checking its existence
+func (s *Supplicant) Interface(path dbus.ObjectPath) *Interface {
+	return &Interface{object{s: s, path: path, iface: InterfaceIface}}
+}
+
+// Interfaces returns all interfaces managed by the supplicant
+func (s *Supplicant) Interfaces(ctx context.Context) ([]*Interface, error) {
+	v, err := s.get(ctx, "Interfaces")
+	if err != nil {
+		return nil, err
+	}
+	paths, _ := v.Value().([]dbus.ObjectPath)
+	ifaces := make([]*Interface, 0, len(paths))
+	for _, p := range paths {
+		ifaces = append(ifaces, s.Interface(p))
+	}
+	return ifaces, nil
+}
+
+// InterfaceInfos fetches the properties of all managed interfaces.
+// Interfaces which vanish while they are fetched are left out.
+func (s *Supplicant) InterfaceInfos(ctx context.Context) ([]*InterfaceInfo, error) {
+	ifaces, err := s.Interfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*InterfaceInfo, len(ifaces))
+	err = s.parallel(ctx, len(ifaces), func(ctx context.Context, idx int) (err error) {
+		infos[idx], err = ifaces[idx].Info(ctx)
+		if errors.Is(err, ErrUnknownObject) {
+			err = nil
+		}
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := infos[:0]
+	for _, info := range infos {
+		if info != nil {
+			result = append(result, info)
+		}
+	}
+	return result, nil
+}
+
+// Properties fetches all supplicant-wide properties with a single call
+func (s *Supplicant) Properties(ctx context.Context) (map[string]dbus.Variant, error) {
+	return s.getAll(ctx)
+}
+
+// Property returns the raw value of the named supplicant-wide property
+func (s *Supplicant) Property(ctx context.Context, name string) (interface{}, error) {
+	v, err := s.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return v.Value(), nil
+}
+
+// SetProperty changes the named supplicant-wide property, e.g.
+// DebugLevel
+func (s *Supplicant) SetProperty(ctx context.Context, name string, value interface{}) error {
+	return s.set(ctx, name, value)
+}
+
+// DescribeProperties returns the supplicant-wide properties sorted by
+// name
+func (s *Supplicant) DescribeProperties(ctx context.Context) ([]PropertyDesc, error) {
+	return s.describe(ctx)
+}
+
+// GetInterface looks up the managed interface with the given name
+func (s *Supplicant) GetInterface(ctx context.Context, ifname string) (*Interface, error) {
+	var p dbus.ObjectPath
+	if err := s.call(ctx, "GetInterface", ifname).Store(&p); err != nil {
+		return nil, err
+	}
+	return s.Interface(p), nil
+}
+
+// InterfaceArgs are the arguments of CreateInterface
+type InterfaceArgs struct {
+	Ifname       string
+	ConfigFile   string
+	Driver       string
+	BridgeIfname string
+}
+
+func (a InterfaceArgs) dict() map[string]interface{} {
+	d := map[string]interface{}{"Ifname": a.Ifname}
+	for k, v := range map[string]string{"ConfigFile": a.ConfigFile, "Driver": a.Driver, "BridgeIfname": a.BridgeIfname} {
+		if len(v) > 0 {
+			d[k] = v
+		}
+	}
+	return d
+}
+
+// CreateInterface puts a network interface under supplicant management
+func (s *Supplicant) CreateInterface(ctx context.Context, args InterfaceArgs) (*Interface, error) {
+	var p dbus.ObjectPath
+	if err := s.call(ctx, "CreateInterface", args.dict()).Store(&p); err != nil {
+		return nil, err
+	}
+	return s.Interface(p), nil
+}
+
+// RemoveInterface releases the interface from supplicant management
+func (s *Supplicant) RemoveInterface(ctx context.Context, i *Interface) error {
+	return s.call(ctx, "RemoveInterface", i.path).Err
+}
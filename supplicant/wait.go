@@ -0,0 +1,161 @@
+package supplicant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// StateError is returned by WaitState if the interface does not reach the
+// wanted state. It records the last state seen.
+type StateError struct {
+	Want             string
+	State            string
+	DisconnectReason int32
+	// Err is the reason for giving up, usually the error of the context
+	Err error
+}
+
+func (e *StateError) Error() string {
+	msg := fmt.Sprintf("interface did not reach state %s: state %s", e.Want, e.State)
+	if e.DisconnectReason != 0 {
+		msg += fmt.Sprintf(", disconnect reason %d", e.DisconnectReason)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *StateError) Unwrap() error {
+	return e.Err
+}
+
+// WaitState blocks until the State property of the interface equals
+// state. It is driven by the PropertiesChanged signal, so the state is
+// read only once. If ctx ends first, a *StateError wrapping the error of
+// ctx is returned.
+func (i *Interface) WaitState(ctx context.Context, state string) (*InterfaceInfo, error) {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(i.path),
+		dbus.WithMatchInterface(propertiesIface),
+		dbus.WithMatchMember("PropertiesChanged"),
+	}
+	conn := i.s.conn
+	if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+		return nil, err
+	}
+	defer conn.RemoveMatchSignal(match...)
+	sigch := make(chan *dbus.Signal, 16)
+	conn.Signal(sigch)
+	defer conn.RemoveSignal(sigch)
+
+	/* Read the state after subscribing to not miss a change */
+	info, err := i.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for info.State != state {
+		select {
+		case sig := <-sigch:
+			if sig.Path != i.path || sig.Name != propertiesIface+".PropertiesChanged" || len(sig.Body) < 2 {
+				continue
+			}
+			if name, _ := sig.Body[0].(string); name != InterfaceIface {
+				continue
+			}
+			changed, _ := sig.Body[1].(map[string]dbus.Variant)
+			if v, ok := changed["State"].Value().(string); ok {
+				info.State = v
+			}
+			if v, ok := changed["DisconnectReason"].Value().(int32); ok {
+				info.DisconnectReason = v
+			}
+			if v, ok := changed["CurrentBSS"].Value().(dbus.ObjectPath); ok {
+				info.CurrentBSS = v
+			}
+			if v, ok := changed["CurrentNetwork"].Value().(dbus.ObjectPath); ok {
+				info.CurrentNetwork = v
+			}
+			if v, ok := changed["CurrentAuthMode"].Value().(string); ok {
+				info.CurrentAuthMode = v
+			}
+		case <-ctx.Done():
+			return info, &StateError{Want: state, State: info.State, DisconnectReason: info.DisconnectReason, Err: ctx.Err()}
+		}
+	}
+	return info, nil
+}
+
+// ErrScanFailed is returned by ScanAndWait and WaitScan if the ScanDone
+// signal reports a failed scan
+var ErrScanFailed = errors.New("scan failed")
+
+// watchScanDone subscribes to the ScanDone signal of the interface. The
+// returned function blocks until the signal arrives or ctx ends, stop
+// removes the subscription.
+func (i *Interface) watchScanDone(ctx context.Context) (wait func(context.Context) error, stop func(), err error) {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(i.path),
+		dbus.WithMatchInterface(InterfaceIface),
+		dbus.WithMatchMember("ScanDone"),
+	}
+	conn := i.s.conn
+	if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+		return nil, nil, err
+	}
+	sigch := make(chan *dbus.Signal, 16)
+	conn.Signal(sigch)
+	stop = func() {
+		conn.RemoveSignal(sigch)
+		conn.RemoveMatchSignal(match...)
+	}
+	wait = func(ctx context.Context) error {
+		for {
+			select {
+			case sig := <-sigch:
+				if sig.Path != i.path || sig.Name != InterfaceIface+".ScanDone" || len(sig.Body) < 1 {
+					continue
+				}
+				if success, _ := sig.Body[0].(bool); !success {
+					return ErrScanFailed
+				}
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("waiting for scan results: %w", ctx.Err())
+			}
+		}
+	}
+	return wait, stop, nil
+}
+
+// ScanAndWait triggers a scan and blocks until its ScanDone signal. The
+// signal is subscribed before the scan is requested, so a fast scan is
+// not missed. A failed scan returns ErrScanFailed.
+func (i *Interface) ScanAndWait(ctx context.Context, args ScanArgs) error {
+	wait, stop, err := i.watchScanDone(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	if err := i.Scan(ctx, args); err != nil {
+		return err
+	}
+	return wait(ctx)
+}
+
+// WaitScan blocks until a running scan of the interface is done and
+// returns immediately if the interface is not scanning. A failed scan
+// returns ErrScanFailed.
+func (i *Interface) WaitScan(ctx context.Context) error {
+	wait, stop, err := i.watchScanDone(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	/* Read the property after subscribing to not miss the signal */
+	scanning, err := i.Scanning(ctx)
+	if err != nil || !scanning {
+		return err
+	}
+	return wait(ctx)
+}
@@ -0,0 +1,108 @@
+package supplicant
+
+import (
+	"context"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// BSSEventType tells what happened to a BSS
+type BSSEventType int
+
+const (
+	// BSSAdded is sent for a new BSS, Info holds all its properties
+	BSSAdded BSSEventType = iota
+	// BSSRemoved is sent when a BSS vanished from the scan results
+	BSSRemoved
+	// BSSChanged is sent when properties of a BSS changed, Changed holds
+	// the new values
+	BSSChanged
+	// ScanDone is sent at the end of a scan, Success reports its result
+	ScanDone
+)
+
+// BSSEvent is an event of WatchBSSs
+type BSSEvent struct {
+	Type    BSSEventType
+	Path    dbus.ObjectPath
+	Info    *BSSInfo
+	Changed map[string]dbus.Variant
+	Success bool
+}
+
+// WatchBSSs sends the changes of the BSS list of the interface, as
+// signaled by BSSAdded, BSSRemoved, ScanDone and the PropertiesChanged
+// signals of the BSSs, to the returned channel. The channel is closed
+// when ctx ends.
+func (i *Interface) WatchBSSs(ctx context.Context) (<-chan BSSEvent, error) {
+	conn := i.s.conn
+	matches := [][]dbus.MatchOption{
+		{dbus.WithMatchObjectPath(i.path), dbus.WithMatchInterface(InterfaceIface)},
+		{dbus.WithMatchPathNamespace(i.path), dbus.WithMatchInterface(propertiesIface), dbus.WithMatchMember("PropertiesChanged")},
+	}
+	for n, match := range matches {
+		if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+			for _, m := range matches[:n] {
+				conn.RemoveMatchSignal(m...)
+			}
+			return nil, err
+		}
+	}
+	sigch := make(chan *dbus.Signal, 64)
+	conn.Signal(sigch)
+	events := make(chan BSSEvent, 64)
+	go func() {
+		defer close(events)
+		defer func() {
+			conn.RemoveSignal(sigch)
+			for _, m := range matches {
+				conn.RemoveMatchSignal(m...)
+			}
+		}()
+		for {
+			var sig *dbus.Signal
+			select {
+			case sig = <-sigch:
+			case <-ctx.Done():
+				return
+			}
+			ev, ok := i.bssEvent(sig)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// bssEvent converts a signal to an event of WatchBSSs
+func (i *Interface) bssEvent(sig *dbus.Signal) (ev BSSEvent, ok bool) {
+	switch {
+	case sig.Path == i.path && sig.Name == InterfaceIface+".BSSAdded" && len(sig.Body) >= 2:
+		ev.Type = BSSAdded
+		ev.Path, _ = sig.Body[0].(dbus.ObjectPath)
+		props, _ := sig.Body[1].(map[string]dbus.Variant)
+		ev.Info = newBSSInfo(ev.Path, props)
+	case sig.Path == i.path && sig.Name == InterfaceIface+".BSSRemoved" && len(sig.Body) >= 1:
+		ev.Type = BSSRemoved
+		ev.Path, _ = sig.Body[0].(dbus.ObjectPath)
+	case sig.Path == i.path && sig.Name == InterfaceIface+".ScanDone" && len(sig.Body) >= 1:
+		ev.Type = ScanDone
+		ev.Success, _ = sig.Body[0].(bool)
+	case sig.Name == propertiesIface+".PropertiesChanged" && len(sig.Body) >= 2:
+		if name, _ := sig.Body[0].(string); name != BSSIface {
+			return ev, false
+		}
+		ev.Type = BSSChanged
+		ev.Path = sig.Path
+		ev.Changed, _ = sig.Body[1].(map[string]dbus.Variant)
+	default:
+		return ev, false
+	}
+	return ev, true
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"jp.net/wpactl/supplicant"
+)
+
+// trendLength is the number of signal levels kept per BSS
+const trendLength = 8
+
+// watchedBSS is a row of ´scan watch´
+type watchedBSS struct {
+	info     *supplicant.BSSInfo
+	added    time.Time
+	vanished time.Time
+	signals  []int16
+}
+
+func (w *watchedBSS) record_signal() {
+	w.signals = append(w.signals, w.info.Signal)
+	if len(w.signals) > trendLength {
+		w.signals = w.signals[len(w.signals)-trendLength:]
+	}
+}
+
+// trend compares the current signal with the oldest one kept
+func (w *watchedBSS) trend() string {
+	if len(w.signals) < 2 {
+		return " "
+	}
+	switch delta := w.signals[len(w.signals)-1] - w.signals[0]; {
+	case delta >= 3:
+		return "↑"
+	case delta <= -3:
+		return "↓"
+	}
+	return "→"
+}
+
+// sparkline renders the kept signal levels between -90 and -30 dBm
+func (w *watchedBSS) sparkline() string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	var sb strings.Builder
+	for _, s := range w.signals {
+		idx := (int(s) + 90) * len(levels) / 60
+		if idx < 0 {
+			idx = 0
+		} else if idx >= len(levels) {
+			idx = len(levels) - 1
+		}
+		sb.WriteRune(levels[idx])
+	}
+	return sb.String()
+}
+
+type bssEventOutput struct {
+	Event string     `json:"event" yaml:"event"`
+	BSS   *bssOutput `json:"bss" yaml:"bss"`
+}
+
+// scanWatch holds the state of ´scan watch´
+type scanWatch struct {
+	ce      *cliExtended
+	ifname  string
+	bsss    map[dbus.ObjectPath]*watchedBSS
+	linger  time.Duration
+	color   bool
+	status  string
+	scanned time.Time
+}
+
+func (sw *scanWatch) event(name string, w *watchedBSS) error {
+	if sw.ce.text_output() {
+		return nil
+	}
+	return sw.ce.print_output("bss-event", bssEventOutput{Event: name, BSS: new_bss_output(w.info)}, nil)
+}
+
+// handle applies an event and reports whether the table changed
+func (sw *scanWatch) handle(ev supplicant.BSSEvent) (bool, error) {
+	now := time.Now()
+	switch ev.Type {
+	case supplicant.BSSAdded:
+		w := &watchedBSS{info: ev.Info, added: now}
+		w.record_signal()
+		sw.bsss[ev.Path] = w
+		return true, sw.event("added", w)
+	case supplicant.BSSRemoved:
+		if w, ok := sw.bsss[ev.Path]; ok && w.vanished.IsZero() {
+			w.vanished = now
+			return true, sw.event("removed", w)
+		}
+	case supplicant.BSSChanged:
+		if w, ok := sw.bsss[ev.Path]; ok {
+			w.info.Update(ev.Changed)
+			if _, ok := ev.Changed["Signal"]; ok {
+				w.record_signal()
+			}
+			return true, sw.event("changed", w)
+		}
+	case supplicant.ScanDone:
+		sw.scanned = now
+		sw.status = "scan done"
+		if !ev.Success {
+			sw.status = "scan failed"
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// expire drops vanished BSSs after the linger time
+func (sw *scanWatch) expire() bool {
+	changed := false
+	for path, w := range sw.bsss {
+		if !w.vanished.IsZero() && time.Since(w.vanished) > sw.linger {
+			delete(sw.bsss, path)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (sw *scanWatch) paint(code, text string) string {
+	if !sw.color {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+func (sw *scanWatch) draw() {
+	rows := make([]*watchedBSS, 0, len(sw.bsss))
+	for _, w := range sw.bsss {
+		rows = append(rows, w)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].info.Signal != rows[j].info.Signal {
+			return rows[i].info.Signal > rows[j].info.Signal
+		}
+		return rows[i].info.Path < rows[j].info.Path
+	})
+	var sb strings.Builder
+	if sw.color {
+		/* Home cursor and clear the screen */
+		sb.WriteString("\033[H\033[2J")
+	}
+	fmt.Fprintf(&sb, "Scan watch on %s, %d BSSs, %s %s\n", sw.ifname, len(rows), sw.status, time.Now().Format("15:04:05"))
+	sb.WriteString("  SSID                             BSSID        Freq Sig  Trend    Age Flags\n")
+	sb.WriteString("================================================================================\n")
+	for _, w := range rows {
+		info := w.info
+		mark := " "
+		line := fmt.Sprintf("%-32s %02x %4d %4d %s%-8s %3d %v", info.SSID, []byte(info.BSSID), info.Frequency, info.Signal, w.trend(), w.sparkline(), info.Age, bss_security(info))
+		switch {
+		case !w.vanished.IsZero():
+			mark, line = "-", sw.paint("31;9", line)
+		case time.Since(w.added) <= sw.linger:
+			mark, line = "+", sw.paint("32", line)
+		}
+		fmt.Fprintf(&sb, "%s %s\n", mark, line)
+	}
+	if !sw.color {
+		sb.WriteString("\n")
+	}
+	fmt.Print(sb.String())
+}
+
+// is_terminal reports whether stdout is a terminal
+func is_terminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (ce *cliExtended) scan_watch() error {
+	ifname, iface, err := ce.get_iface()
+	if err != nil {
+		return err
+	}
+	interval := ce.Duration("interval")
+	sw := &scanWatch{
+		ce:     ce,
+		ifname: ifname,
+		bsss:   make(map[dbus.ObjectPath]*watchedBSS),
+		linger: ce.Duration("linger"),
+		color:  is_terminal() && len(os.Getenv("NO_COLOR")) == 0,
+		status: "waiting for scan",
+	}
+	/* Subscribe before reading the list to not miss a change */
+	events, err := iface.WatchBSSs(ce.ctx())
+	if err != nil {
+		return err
+	}
+	infos, err := iface.BSSInfos(ce.ctx())
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		w := &watchedBSS{info: info}
+		w.record_signal()
+		sw.bsss[info.Path] = w
+	}
+	trigger := func() {
+		if interval <= 0 {
+			return
+		}
+		if err := iface.Scan(ce.ctx(), supplicant.ScanArgs{}); err != nil {
+			sw.status = "scan not started: " + err.Error()
+		} else {
+			sw.status = "scanning"
+		}
+	}
+	trigger()
+	var scan_timer <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		scan_timer = ticker.C
+	}
+	/* Redraw at most twice a second */
+	redraw := time.NewTicker(500 * time.Millisecond)
+	defer redraw.Stop()
+	dirty := true
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				/* The context ended, i.e. SIGINT or SIGTERM */
+				return nil
+			}
+			changed, err := sw.handle(ev)
+			if err != nil {
+				return err
+			}
+			dirty = dirty || changed
+		case <-scan_timer:
+			trigger()
+			dirty = true
+		case <-redraw.C:
+			dirty = sw.expire() || dirty
+			if dirty && ce.text_output() {
+				sw.draw()
+			}
+			dirty = false
+		case <-ce.ctx().Done():
+			return nil
+		}
+	}
+}